@@ -21,7 +21,24 @@ import (
 
 // Information holds the build information.
 type Information struct {
-	Name, Version, Branch, Commit, Date, Go string
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Branch  string `json:"branch"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+	Go      string `json:"go"`
+
+	// Dirty reports that the working tree had uncommitted changes when the
+	// binary was built.
+	Dirty bool `json:"dirty"`
+
+	// Deps lists the dependency modules of the binary, see Detect.
+	Deps []Module `json:"deps,omitempty"`
+
+	// Settings holds the build settings of the binary, e.g. GOOS and
+	// -ldflags, see Detect. As they may reveal secrets, they should not be
+	// exposed publicly.
+	Settings map[string]string `json:"settings,omitempty"`
 }
 
 // Info populates the build information.
@@ -45,6 +62,7 @@ func (i Information) Fields() []interface{} {
 		"commit", i.Commit,
 		"date", i.Date,
 		"go", i.Go,
+		"dirty", i.Dirty,
 	}
 }
 
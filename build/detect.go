@@ -0,0 +1,105 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build // import "arcadium.dev/core/build"
+
+import (
+	"path"
+	"runtime"
+	"runtime/debug"
+)
+
+const (
+	develVersion = "(devel)"
+)
+
+var (
+	readBuildInfo = debug.ReadBuildInfo
+)
+
+// Module describes a dependency module of the binary.
+type Module struct {
+	Path    string  `json:"path"`
+	Version string  `json:"version"`
+	Sum     string  `json:"sum,omitempty"`
+	Replace *Module `json:"replace,omitempty"`
+}
+
+// Detect returns the build information recorded in the binary by the go
+// toolchain, see Information.Detect.
+func Detect() Information {
+	return Information{Go: runtime.Version()}.Detect()
+}
+
+// Detect fills the missing values of the build information from the build
+// information recorded in the binary by the go toolchain: the name of the main
+// package, the module version, and the vcs revision, time and modified flag.
+// The branch is not recorded by the toolchain. The dependency modules and the
+// build settings are also added.
+func (i Information) Detect() Information {
+	bi, ok := readBuildInfo()
+	if !ok {
+		return i
+	}
+	return detect(i, bi)
+}
+
+func detect(i Information, bi *debug.BuildInfo) Information {
+	settings := make(map[string]string, len(bi.Settings))
+	for _, s := range bi.Settings {
+		settings[s.Key] = s.Value
+	}
+
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	if bi.Path != "" {
+		fill(&i.Name, path.Base(bi.Path))
+	}
+	if bi.Main.Version != develVersion {
+		fill(&i.Version, bi.Main.Version)
+	}
+	fill(&i.Commit, settings["vcs.revision"])
+	fill(&i.Date, settings["vcs.time"])
+	fill(&i.Go, bi.GoVersion)
+	if settings["vcs.modified"] == "true" {
+		i.Dirty = true
+	}
+
+	if len(bi.Deps) > 0 {
+		i.Deps = make([]Module, 0, len(bi.Deps))
+		for _, d := range bi.Deps {
+			i.Deps = append(i.Deps, module(d))
+		}
+	}
+	if len(settings) > 0 {
+		i.Settings = settings
+	}
+	return i
+}
+
+func module(m *debug.Module) Module {
+	mod := Module{
+		Path:    m.Path,
+		Version: m.Version,
+		Sum:     m.Sum,
+	}
+	if m.Replace != nil {
+		r := module(m.Replace)
+		mod.Replace = &r
+	}
+	return mod
+}
@@ -0,0 +1,95 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"reflect"
+	"runtime/debug"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.18",
+		Path:      "arcadium.dev/example/cmd/example",
+		Main:      debug.Module{Path: "arcadium.dev/example", Version: "v1.2.3"},
+		Deps: []*debug.Module{
+			{Path: "github.com/gorilla/mux", Version: "v1.8.0", Sum: "h1:sum"},
+			{Path: "arcadium.dev/core", Version: "v0.1.0", Replace: &debug.Module{Path: "../core", Version: ""}},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "GOOS", Value: "linux"},
+			{Key: "vcs.revision", Value: "abc123"},
+			{Key: "vcs.time", Value: "2022-01-01T00:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	t.Run("missing values", func(t *testing.T) {
+		actual := detect(Information{}, bi)
+
+		expected := Information{
+			Name:    "example",
+			Version: "v1.2.3",
+			Commit:  "abc123",
+			Date:    "2022-01-01T00:00:00Z",
+			Go:      "go1.18",
+			Dirty:   true,
+			Deps: []Module{
+				{Path: "github.com/gorilla/mux", Version: "v1.8.0", Sum: "h1:sum"},
+				{Path: "arcadium.dev/core", Version: "v0.1.0", Replace: &Module{Path: "../core"}},
+			},
+			Settings: map[string]string{
+				"GOOS":         "linux",
+				"vcs.revision": "abc123",
+				"vcs.time":     "2022-01-01T00:00:00Z",
+				"vcs.modified": "true",
+			},
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("\nExpected: %+v\nActual:   %+v", expected, actual)
+		}
+	})
+
+	t.Run("injected values", func(t *testing.T) {
+		actual := detect(Info("Name", "Version", "Branch", "Commit", "Date"), bi)
+
+		if actual.Name != "Name" || actual.Version != "Version" || actual.Branch != "Branch" ||
+			actual.Commit != "Commit" || actual.Date != "Date" {
+			t.Errorf("Unexpected information: %+v", actual)
+		}
+		if !actual.Dirty || len(actual.Deps) != 2 || actual.Settings["GOOS"] != "linux" {
+			t.Errorf("Unexpected information: %+v", actual)
+		}
+	})
+
+	t.Run("devel version", func(t *testing.T) {
+		bi := &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}
+
+		if actual := detect(Information{}, bi); actual.Version != "" {
+			t.Errorf("Unexpected version: %s", actual.Version)
+		}
+	})
+
+	t.Run("without build info", func(t *testing.T) {
+		defer func(f func() (*debug.BuildInfo, bool)) { readBuildInfo = f }(readBuildInfo)
+		readBuildInfo = func() (*debug.BuildInfo, bool) { return nil, false }
+
+		info := Info("Name", "Version", "Branch", "Commit", "Date")
+		if actual := info.Detect(); !reflect.DeepEqual(actual, info) {
+			t.Errorf("\nExpected: %+v\nActual:   %+v", info, actual)
+		}
+	})
+}
@@ -0,0 +1,351 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build // import "arcadium.dev/core/build"
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"arcadium.dev/core/errors"
+)
+
+var (
+	// semverRE matches a semantic version, see https://semver.org, with an
+	// optional v prefix.
+	semverRE = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+	// partialRE matches the possibly partial versions of a constraint, e.g. 2
+	// or v1.4.
+	partialRE = regexp.MustCompile(`^v?(0|[1-9]\d*)(?:\.(0|[1-9]\d*)(?:\.(0|[1-9]\d*))?)?$`)
+
+	// pseudoRE matches the prerelease of a go module pseudo-version, e.g.
+	// v0.0.0-20220101000000-abcdefabcdef or v1.2.4-0.20220101000000-abcdefabcdef.
+	pseudoRE = regexp.MustCompile(`^(?:|.*\.)(?:0\.)?\d{14}-[0-9a-f]{12}$`)
+)
+
+type (
+	// Semver is a semantic version, see https://semver.org.
+	Semver struct {
+		Major, Minor, Patch uint64
+
+		// Prerelease holds the dot separated prerelease identifiers, e.g. rc.1.
+		Prerelease string
+
+		// Build holds the dot separated build metadata, which is ignored by
+		// comparison.
+		Build string
+	}
+
+	// Constraint is a set of version comparisons, e.g. ">=1.4, <2". A version
+	// satisfies the constraint when it satisfies all of the comparisons of
+	// any of the || separated alternatives.
+	Constraint struct {
+		raw          string
+		alternatives [][]comparison
+	}
+
+	comparison struct {
+		op      string
+		version Semver
+
+		// upper bounds the range excluded by the != operator given a partial
+		// version.
+		upper Semver
+	}
+)
+
+// ParseSemver parses a semantic version, with an optional v prefix.
+func ParseSemver(s string) (Semver, error) {
+	m := semverRE.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Semver{}, fmt.Errorf("%w: invalid semantic version: %q", errors.ErrInvalidArgument, s)
+	}
+	v := Semver{Prerelease: m[4], Build: m[5]}
+	for i, n := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		var err error
+		if *n, err = strconv.ParseUint(m[i+1], 10, 64); err != nil {
+			return Semver{}, fmt.Errorf("%w: invalid semantic version: %q", errors.ErrInvalidArgument, s)
+		}
+	}
+	return v, nil
+}
+
+// Semver parses the version of the build information.
+func (i Information) Semver() (Semver, error) {
+	return ParseSemver(i.Version)
+}
+
+// String returns the version, with a v prefix.
+func (v Semver) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 when v is less than, equal to, or greater than
+// w, by semver precedence.
+func (v Semver) Compare(w Semver) int {
+	for _, c := range [][2]uint64{{v.Major, w.Major}, {v.Minor, w.Minor}, {v.Patch, w.Patch}} {
+		switch {
+		case c[0] < c[1]:
+			return -1
+		case c[0] > c[1]:
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, w.Prerelease)
+}
+
+// LessThan reports whether v precedes w.
+func (v Semver) LessThan(w Semver) bool {
+	return v.Compare(w) < 0
+}
+
+// IsPrerelease reports whether v is a prerelease version.
+func (v Semver) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// IsPseudo reports whether v is a go module pseudo-version, which identifies
+// an untagged commit, e.g. v0.0.0-20220101000000-abcdefabcdef.
+func (v Semver) IsPseudo() bool {
+	return pseudoRE.MatchString(v.Prerelease)
+}
+
+// comparePrerelease compares the prerelease identifiers of two versions with
+// equal major, minor and patch versions.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		// A release has a higher precedence than its prereleases.
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier compares prerelease identifiers: numeric identifiers
+// numerically, and with a lower precedence than alphanumeric identifiers.
+func compareIdentifier(a, b string) int {
+	an, aerr := strconv.ParseUint(a, 10, 64)
+	bn, berr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aerr == nil && berr == nil:
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	case aerr == nil:
+		return -1
+	case berr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// ParseConstraint parses a constraint, e.g. ">=1.4, <2" or "~1.2 || ^2.1".
+//
+// The comparison operators are =, !=, >, >=, < and <=, and = when the
+// operator is omitted. A partial version stands for all of the versions it
+// matches, e.g. >1.4 requires 1.5.0 or later, and <2 excludes 2.x.y and the
+// prereleases of 2.0.0. The ~ operator allows patch updates, and the ^
+// operator allows minor and patch updates, excluding the prereleases of the
+// next version.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	for _, alternative := range strings.Split(s, "||") {
+		var comparisons []comparison
+		for _, term := range strings.Split(alternative, ",") {
+			cs, err := parseComparison(strings.TrimSpace(term))
+			if err != nil {
+				return Constraint{}, fmt.Errorf("%w: invalid constraint: %q: %s", errors.ErrInvalidArgument, s, err)
+			}
+			comparisons = append(comparisons, cs...)
+		}
+		c.alternatives = append(c.alternatives, comparisons)
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint, but panics if the constraint
+// cannot be parsed. It simplifies the initialization of global variables.
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Check reports whether the version satisfies the constraint.
+func (c Constraint) Check(v Semver) bool {
+	for _, comparisons := range c.alternatives {
+		ok := true
+		for _, cmp := range comparisons {
+			if !cmp.check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the constraint as given to ParseConstraint.
+func (c Constraint) String() string {
+	return c.raw
+}
+
+func parseComparison(term string) ([]comparison, error) {
+	op := ""
+	for _, o := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(term, o) {
+			op = o
+			break
+		}
+	}
+	version := strings.TrimSpace(strings.TrimPrefix(term, op))
+	if op == "" {
+		op = "="
+	}
+
+	// Full versions, possibly with prereleases, are compared as is.
+	if v, err := ParseSemver(version); err == nil {
+		switch op {
+		case "~":
+			return []comparison{{op: ">=", version: v}, {op: "<", version: lowest(Semver{Major: v.Major, Minor: v.Minor + 1})}}, nil
+		case "^":
+			return []comparison{{op: ">=", version: v}, {op: "<", version: caret(v)}}, nil
+		}
+		return []comparison{{op: op, version: v}}, nil
+	}
+
+	m := partialRE.FindStringSubmatch(version)
+	if m == nil {
+		return nil, fmt.Errorf("invalid version: %q", version)
+	}
+	var v Semver
+	parts := 0
+	for i, n := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		if m[i+1] == "" {
+			break
+		}
+		var err error
+		if *n, err = strconv.ParseUint(m[i+1], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid version: %q", version)
+		}
+		parts++
+	}
+
+	// next is the lowest version beyond the range of the partial version,
+	// e.g. 2.0.0 for 1 and 1.5.0 for 1.4.
+	next := Semver{Major: v.Major + 1}
+	if parts == 2 {
+		next = Semver{Major: v.Major, Minor: v.Minor + 1}
+	}
+
+	switch op {
+	case "=", "~":
+		return []comparison{{op: ">=", version: v}, {op: "<", version: lowest(next)}}, nil
+	case "!=":
+		return []comparison{{op: "!=", version: v, upper: next}}, nil
+	case ">":
+		return []comparison{{op: ">=", version: next}}, nil
+	case "<":
+		return []comparison{{op: "<", version: lowest(v)}}, nil
+	case "<=":
+		return []comparison{{op: "<", version: lowest(next)}}, nil
+	case "^":
+		// The missing parts are not zeros, e.g. ^0 allows 0.x.y and ^0.0
+		// allows 0.0.y.
+		upper := lowest(next)
+		if v.Major > 0 {
+			upper = caret(v)
+		}
+		return []comparison{{op: ">=", version: v}, {op: "<", version: upper}}, nil
+	}
+	return []comparison{{op: op, version: v}}, nil
+}
+
+// caret returns the lowest version excluded by the ^ operator.
+func caret(v Semver) Semver {
+	switch {
+	case v.Major > 0:
+		return lowest(Semver{Major: v.Major + 1})
+	case v.Minor > 0:
+		return lowest(Semver{Minor: v.Minor + 1})
+	}
+	return lowest(Semver{Patch: v.Patch + 1})
+}
+
+// lowest returns the lowest prerelease of the version, so an upper bound
+// excludes the prereleases of the bound, e.g. <2 excludes 2.0.0-rc.1.
+func lowest(v Semver) Semver {
+	v.Prerelease = "0"
+	return v
+}
+
+func (c comparison) check(v Semver) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		if c.upper != (Semver{}) {
+			return cmp < 0 || !v.LessThan(c.upper)
+		}
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
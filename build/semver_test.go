@@ -0,0 +1,189 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build_test
+
+import (
+	"errors"
+	"testing"
+
+	"arcadium.dev/core/build"
+	cerrors "arcadium.dev/core/errors"
+)
+
+func TestParseSemver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		version  string
+		expected build.Semver
+		err      bool
+	}{
+		{version: "1.2.3", expected: build.Semver{Major: 1, Minor: 2, Patch: 3}},
+		{version: "v1.2.3", expected: build.Semver{Major: 1, Minor: 2, Patch: 3}},
+		{version: "v1.0.0-rc.1+build.5", expected: build.Semver{Major: 1, Prerelease: "rc.1", Build: "build.5"}},
+		{version: "v0.0.0-20220101000000-abcdefabcdef", expected: build.Semver{Prerelease: "20220101000000-abcdefabcdef"}},
+		{version: "1.2", err: true},
+		{version: "01.2.3", err: true},
+		{version: "1.2.3-01", err: true},
+		{version: "1.2.3-", err: true},
+		{version: "", err: true},
+	}
+
+	for _, test := range tests {
+		v, err := build.ParseSemver(test.version)
+		if test.err {
+			if !errors.Is(err, cerrors.ErrInvalidArgument) {
+				t.Errorf("%s: Expected an invalid argument error, actual: %v", test.version, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %s", test.version, err)
+		}
+		if v != test.expected {
+			t.Errorf("\nExpected: %+v\nActual:   %+v", test.expected, v)
+		}
+	}
+}
+
+func TestSemverString(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"v1.2.3", "v1.0.0-rc.1", "v1.0.0-rc.1+build.5", "v1.0.0+build"} {
+		v, err := build.ParseSemver(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if v.String() != s {
+			t.Errorf("\nExpected: %s\nActual:   %s", s, v.String())
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	t.Parallel()
+
+	// Ordered by precedence, see https://semver.org/#spec-item-11.
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := build.ParseSemver(ordered[i])
+			b, _ := build.ParseSemver(ordered[j])
+
+			expected := 0
+			switch {
+			case i < j:
+				expected = -1
+			case i > j:
+				expected = 1
+			}
+			if actual := a.Compare(b); actual != expected {
+				t.Errorf("%s compared to %s: Expected %d, Actual: %d", a, b, expected, actual)
+			}
+		}
+	}
+
+	a, _ := build.ParseSemver("1.0.0+a")
+	b, _ := build.ParseSemver("1.0.0+b")
+	if a.Compare(b) != 0 {
+		t.Error("Expected the build metadata to be ignored")
+	}
+}
+
+func TestSemverIsPseudo(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"v0.0.0-20220101000000-abcdefabcdef":        true,
+		"v1.2.4-0.20220101000000-abcdefabcdef":      true,
+		"v1.2.4-rc.1.0.20220101000000-abcdefabcdef": true,
+		"v1.2.3":      false,
+		"v1.2.3-rc.1": false,
+		"v1.2.3-2022": false,
+	}
+	for s, expected := range tests {
+		v, err := build.ParseSemver(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if v.IsPseudo() != expected {
+			t.Errorf("%s: Expected %t, Actual: %t", s, expected, v.IsPseudo())
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		constraint string
+		match      []string
+		nomatch    []string
+	}{
+		{constraint: ">=1.4, <2", match: []string{"1.4.0", "1.9.9"}, nomatch: []string{"1.3.9", "2.0.0", "2.1.0"}},
+		{constraint: "1.4", match: []string{"1.4.0", "1.4.7"}, nomatch: []string{"1.5.0", "1.3.0"}},
+		{constraint: "=1.4.2", match: []string{"1.4.2"}, nomatch: []string{"1.4.3"}},
+		{constraint: ">1.4", match: []string{"1.5.0"}, nomatch: []string{"1.4.9"}},
+		{constraint: "<=1.4", match: []string{"1.4.9"}, nomatch: []string{"1.5.0"}},
+		{constraint: "!=1.4", match: []string{"1.3.9", "1.5.0"}, nomatch: []string{"1.4.0", "1.4.2"}},
+		{constraint: "!=1.4.2", match: []string{"1.4.1"}, nomatch: []string{"1.4.2"}},
+		{constraint: "~1.2.3", match: []string{"1.2.3", "1.2.9"}, nomatch: []string{"1.3.0", "1.2.2"}},
+		{constraint: "^1.2.3", match: []string{"1.2.3", "1.9.0"}, nomatch: []string{"2.0.0", "1.2.2"}},
+		{constraint: "^0.2.3", match: []string{"0.2.9"}, nomatch: []string{"0.3.0"}},
+		{constraint: "^1.2", match: []string{"1.2.0", "1.9.0"}, nomatch: []string{"1.1.9", "2.0.0"}},
+		{constraint: "^0.2", match: []string{"0.2.0", "0.2.9"}, nomatch: []string{"0.3.0"}},
+		{constraint: "^0", match: []string{"0.0.1", "0.5.0"}, nomatch: []string{"1.0.0", "1.0.0-rc.1"}},
+		{constraint: "^0.0", match: []string{"0.0.1", "0.0.5"}, nomatch: []string{"0.1.0"}},
+		{constraint: "<1 || >=2.1", match: []string{"0.9.0", "2.1.0"}, nomatch: []string{"1.0.0", "2.0.9"}},
+
+		// The prereleases of an upper bound are excluded, unless the bound is a
+		// full version.
+		{constraint: ">=1.4, <2", match: []string{"1.9.9-rc.1"}, nomatch: []string{"2.0.0-rc.1", "2.0.0-0"}},
+		{constraint: "<=1.4", match: []string{"1.4.9-rc.1"}, nomatch: []string{"1.5.0-rc.1"}},
+		{constraint: "1.4", nomatch: []string{"1.5.0-rc.1"}},
+		{constraint: "~1.2.3", nomatch: []string{"1.3.0-rc.1"}},
+		{constraint: "^1.2.3", nomatch: []string{"2.0.0-rc.1"}},
+		{constraint: "^0.2.3", nomatch: []string{"0.3.0-rc.1"}},
+		{constraint: "<2.0.0", match: []string{"2.0.0-rc.1"}},
+	}
+
+	for _, test := range tests {
+		c, err := build.ParseConstraint(test.constraint)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		for _, s := range test.match {
+			v, _ := build.ParseSemver(s)
+			if !c.Check(v) {
+				t.Errorf("Expected %s to satisfy %s", s, test.constraint)
+			}
+		}
+		for _, s := range test.nomatch {
+			v, _ := build.ParseSemver(s)
+			if c.Check(v) {
+				t.Errorf("Expected %s not to satisfy %s", s, test.constraint)
+			}
+		}
+	}
+
+	for _, s := range []string{"", ">=", ">=1.x", "1.2.3.4", ">=1.4,"} {
+		if _, err := build.ParseConstraint(s); !errors.Is(err, cerrors.ErrInvalidArgument) {
+			t.Errorf("%q: Expected an invalid argument error, actual: %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build // import "arcadium.dev/core/build"
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"arcadium.dev/core/errors"
+	"arcadium.dev/core/log"
+)
+
+var (
+	// exit allows for insertion of a mock exit function.
+	exit = os.Exit
+)
+
+// HandleVersion handles the version command and flag given by os.Args, see
+// WriteVersion. When handled, the version is printed to stdout and the process
+// exits.
+func (i Information) HandleVersion() {
+	handled, err := i.WriteVersion(os.Stdout, os.Args[1:])
+	switch {
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	case handled:
+		exit(0)
+	}
+}
+
+// WriteVersion writes the build information to w when the first argument is
+// the version command or flag: version, --version or -version. It is written
+// as by String, as a JSON object of the Fields with --json, or as the version
+// alone with --short. The other arguments are ignored when the first argument
+// is neither.
+func (i Information) WriteVersion(w io.Writer, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch args[0] {
+	case "version", "--version", "-version":
+	default:
+		return false, nil
+	}
+
+	format := ""
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--json", "-json", "--short", "-short":
+			if format != "" {
+				return true, fmt.Errorf("%w: %s cannot be combined with %s", errors.ErrInvalidArgument, arg, format)
+			}
+			format = arg
+		default:
+			return true, fmt.Errorf("%w: unknown version flag: %s", errors.ErrInvalidArgument, arg)
+		}
+	}
+
+	var err error
+	switch format {
+	case "--json", "-json":
+		fields := i.Fields()
+		m := make(map[string]interface{}, len(fields)/2)
+		for j := 0; j+1 < len(fields); j += 2 {
+			m[fields[j].(string)] = fields[j+1]
+		}
+		err = json.NewEncoder(w).Encode(m)
+	case "--short", "-short":
+		_, err = fmt.Fprintln(w, i.Version)
+	default:
+		_, err = fmt.Fprintln(w, i.String())
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to write the version: %w", err)
+	}
+	return true, nil
+}
+
+// LogStartup logs the start of the service with the build information.
+func (i Information) LogStartup(logger log.Logger) {
+	logger.Info(append([]interface{}{"msg", "starting"}, i.Fields()...)...)
+}
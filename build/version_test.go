@@ -0,0 +1,75 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	cerrors "arcadium.dev/core/errors"
+	"arcadium.dev/core/log/logtest"
+)
+
+func TestWriteVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		handled  bool
+		expected string
+		err      bool
+	}{
+		{name: "no args"},
+		{name: "other command", args: []string{"serve", "--version"}},
+		{name: "command", args: []string{"version"}, handled: true, expected: "Testing Version (branch: Branch, commit: Commit, date: Date, go: Go)\n"},
+		{name: "flag", args: []string{"--version"}, handled: true, expected: "Testing Version (branch: Branch, commit: Commit, date: Date, go: Go)\n"},
+		{name: "short", args: []string{"-version", "--short"}, handled: true, expected: "Version\n"},
+		{
+			name: "json", args: []string{"version", "--json"}, handled: true,
+			expected: `{"branch":"Branch","commit":"Commit","date":"Date","dirty":false,"go":"Go","name":"Testing","version":"Version"}` + "\n",
+		},
+		{name: "unknown flag", args: []string{"version", "--yaml"}, handled: true, err: true},
+		{name: "conflicting flags", args: []string{"version", "--json", "--short"}, handled: true, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			handled, err := setup(t).WriteVersion(&b, test.args)
+
+			if handled != test.handled {
+				t.Errorf("\nExpected handled: %t\nActual handled:   %t", test.handled, handled)
+			}
+			if test.err != errors.Is(err, cerrors.ErrInvalidArgument) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if b.String() != test.expected {
+				t.Errorf("\nExpected: %q\nActual:   %q", test.expected, b.String())
+			}
+		})
+	}
+}
+
+func TestLogStartup(t *testing.T) {
+	t.Parallel()
+
+	logger, r := logtest.New(t)
+
+	setup(t).LogStartup(logger)
+
+	r.AssertContains(t, "msg", "starting", "name", "Testing", "version", "Version", "commit", "Commit")
+}
@@ -25,7 +25,29 @@ type (
 	// Logger holds the configuration information for a logger.
 	Logger struct {
 		level  string
+		levels string
 		format string
+		output string
+		syslog Syslog
+		otlp   OTLP
+	}
+
+	// Syslog holds the configuration information for shipping logs to a syslog
+	// collector.
+	Syslog struct {
+		network  string
+		addr     string
+		format   string
+		facility string
+		tag      string
+	}
+
+	// OTLP holds the configuration information for exporting logs to an
+	// OpenTelemetry collector.
+	OTLP struct {
+		endpoint    string
+		headers     map[string]string
+		serviceName string
 	}
 )
 
@@ -42,15 +64,39 @@ func NewLogger(opts ...Option) (Logger, error) {
 	prefix := o.Prefix + logPrefix
 
 	config := struct {
-		Level  string
-		Format string
+		Level          string
+		Levels         string
+		Format         string
+		Output         string
+		SyslogNetwork  string            `split_words:"true"`
+		SyslogAddr     string            `split_words:"true"`
+		SyslogFormat   string            `split_words:"true"`
+		SyslogFacility string            `split_words:"true"`
+		SyslogTag      string            `split_words:"true"`
+		OTLPEndpoint   string            `envconfig:"otlp_endpoint"`
+		OTLPHeaders    map[string]string `envconfig:"otlp_headers"`
+		OTLPService    string            `envconfig:"otlp_service_name"`
 	}{}
 	if err := envconfig.Process(prefix, &config); err != nil {
 		return Logger{}, fmt.Errorf("failed to load %s configuration: %w", prefix, err)
 	}
 	return Logger{
 		level:  strings.TrimSpace(strings.ToLower(config.Level)),
+		levels: strings.TrimSpace(config.Levels),
 		format: strings.TrimSpace(strings.ToLower(config.Format)),
+		output: strings.TrimSpace(strings.ToLower(config.Output)),
+		syslog: Syslog{
+			network:  strings.TrimSpace(strings.ToLower(config.SyslogNetwork)),
+			addr:     strings.TrimSpace(config.SyslogAddr),
+			format:   strings.TrimSpace(strings.ToLower(config.SyslogFormat)),
+			facility: strings.TrimSpace(strings.ToLower(config.SyslogFacility)),
+			tag:      strings.TrimSpace(config.SyslogTag),
+		},
+		otlp: OTLP{
+			endpoint:    strings.TrimSpace(config.OTLPEndpoint),
+			headers:     config.OTLPHeaders,
+			serviceName: strings.TrimSpace(config.OTLPService),
+		},
 	}, nil
 }
 
@@ -60,8 +106,74 @@ func (l Logger) Level() string {
 	return l.level
 }
 
-// Format returns the logging format for the logger. The value is set from the
-// <PREFIX_>LOG_FORMAT environment variable.
+// Levels returns the level overrides for named loggers, as a comma separated
+// list of name=level pairs, e.g. "sql=debug,http=warn". The logger names keep
+// their case, see log.ParseLevels.
+func (l Logger) Levels() string {
+	return l.levels
+}
+
+// Format returns the logging format for the logger, e.g. logfmt, json or otlp.
+// The value is set from the <PREFIX_>LOG_FORMAT environment variable.
 func (l Logger) Format() string {
 	return l.format
 }
+
+// Output returns the destination of the logs, e.g. stdout, stderr or syslog.
+func (l Logger) Output() string {
+	return l.output
+}
+
+// Syslog returns the syslog configuration, used when the output is syslog.
+func (l Logger) Syslog() Syslog {
+	return l.syslog
+}
+
+// Network returns the network used to reach the syslog collector: udp, tcp or
+// tls.
+func (s Syslog) Network() string {
+	return s.network
+}
+
+// Addr returns the address of the syslog collector.
+func (s Syslog) Addr() string {
+	return s.addr
+}
+
+// Format returns the syslog message format: rfc5424 or rfc3164.
+func (s Syslog) Format() string {
+	return s.format
+}
+
+// Facility returns the syslog facility, e.g. user or local0.
+func (s Syslog) Facility() string {
+	return s.facility
+}
+
+// Tag returns the syslog tag.
+func (s Syslog) Tag() string {
+	return s.tag
+}
+
+// OTLP returns the OpenTelemetry configuration, used when the format is otlp.
+func (l Logger) OTLP() OTLP {
+	return l.otlp
+}
+
+// Endpoint returns the OTLP/HTTP logs endpoint of the collector, e.g.
+// http://localhost:4318/v1/logs.
+func (o OTLP) Endpoint() string {
+	return o.endpoint
+}
+
+// Headers returns the headers added to each export request, set as a comma
+// separated list of key:value pairs.
+func (o OTLP) Headers() map[string]string {
+	return o.headers
+}
+
+// ServiceName returns the service.name resource attribute of the exported
+// logs.
+func (o OTLP) ServiceName() string {
+	return o.serviceName
+}
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"arcadium.dev/core/config"
+	"arcadium.dev/core/log"
 )
 
 func TestLog(t *testing.T) {
@@ -48,6 +49,59 @@ func TestLog(t *testing.T) {
 		}
 	})
 
+	t.Run("Levels Env", func(t *testing.T) {
+		t.Setenv("LOG_LEVELS", " SQL=Debug,http=warn ")
+		cfg := setupLogger(t)
+
+		if cfg.Levels() != "SQL=Debug,http=warn" {
+			t.Errorf("Unexpected levels: %s", cfg.Levels())
+		}
+
+		// The logger names keep their case, the levels are parsed regardless
+		// of their case.
+		levels, err := log.ParseLevels(cfg.Levels())
+		if err != nil || len(levels) != 2 || levels["SQL"] != log.LevelDebug || levels["http"] != log.LevelWarn {
+			t.Errorf("Unexpected levels: %+v, %v", levels, err)
+		}
+	})
+
+	t.Run("Syslog Env", func(t *testing.T) {
+		t.Setenv("LOG_OUTPUT", "SYSLOG")
+		t.Setenv("LOG_SYSLOG_NETWORK", "TCP")
+		t.Setenv("LOG_SYSLOG_ADDR", "collector:514")
+		t.Setenv("LOG_SYSLOG_FORMAT", "RFC3164")
+		t.Setenv("LOG_SYSLOG_FACILITY", "Local0")
+		t.Setenv("LOG_SYSLOG_TAG", "arcadium")
+		cfg := setupLogger(t)
+
+		if cfg.Output() != "syslog" {
+			t.Errorf("Unexpected output: %s", cfg.Output())
+		}
+		s := cfg.Syslog()
+		if s.Network() != "tcp" || s.Addr() != "collector:514" || s.Format() != "rfc3164" ||
+			s.Facility() != "local0" || s.Tag() != "arcadium" {
+			t.Errorf("incorrect syslog config: %+v", s)
+		}
+	})
+
+	t.Run("OTLP Env", func(t *testing.T) {
+		t.Setenv("LOG_FORMAT", "OTLP")
+		t.Setenv("LOG_OTLP_ENDPOINT", "http://collector:4318/v1/logs")
+		t.Setenv("LOG_OTLP_HEADERS", "Authorization:Bearer token,X-Tenant:arcadium")
+		t.Setenv("LOG_OTLP_SERVICE_NAME", "arcadium")
+		cfg := setupLogger(t)
+
+		if cfg.Format() != "otlp" {
+			t.Errorf("Unexpected format: %s", cfg.Format())
+		}
+		o := cfg.OTLP()
+		if o.Endpoint() != "http://collector:4318/v1/logs" || o.ServiceName() != "arcadium" ||
+			len(o.Headers()) != 2 || o.Headers()["Authorization"] != "Bearer token" ||
+			o.Headers()["X-Tenant"] != "arcadium" {
+			t.Errorf("incorrect otlp config: %+v", o)
+		}
+	})
+
 	t.Run("WithPrefix", func(t *testing.T) {
 		t.Setenv("PREFIX_LOG_LEVEL", "level")
 		t.Setenv("PREFIX_LOG_FORMAT", "format")
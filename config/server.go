@@ -16,6 +16,8 @@ package config // import "arcadium.dev/core/config
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/kelseyhightower/envconfig"
@@ -24,7 +26,16 @@ import (
 type (
 	// Server holds the configuration settings for a server.
 	Server struct {
-		addr string
+		addr          string
+		adminAddr     string
+		debug         bool
+		debugUser     string
+		debugPassword string
+
+		tlsAddr        string
+		unixSocket     string
+		unixSocketMode os.FileMode
+		redirectAddr   string
 	}
 )
 
@@ -41,14 +52,40 @@ func NewServer(opts ...Option) (Server, error) {
 	prefix := o.Prefix + serverPrefix
 
 	config := struct {
-		Addr string `required:"true"`
+		Addr          string `required:"true"`
+		AdminAddr     string `split_words:"true"`
+		Debug         bool
+		DebugUser     string `split_words:"true"`
+		DebugPassword string `split_words:"true"`
+
+		TLSAddr        string `split_words:"true"`
+		UnixSocket     string `split_words:"true"`
+		UnixSocketMode string `split_words:"true"`
+		RedirectAddr   string `split_words:"true"`
 	}{}
 	if err := envconfig.Process(prefix, &config); err != nil {
 		return Server{}, fmt.Errorf("failed to load %s configuration: %w", prefix, err)
 	}
 
+	var mode uint64
+	if m := strings.TrimSpace(config.UnixSocketMode); m != "" {
+		var err error
+		if mode, err = strconv.ParseUint(m, 8, 32); err != nil {
+			return Server{}, fmt.Errorf("failed to load %s configuration: invalid unix socket mode: %s", prefix, m)
+		}
+	}
+
 	return Server{
-		addr: strings.TrimSpace(config.Addr),
+		addr:          strings.TrimSpace(config.Addr),
+		adminAddr:     strings.TrimSpace(config.AdminAddr),
+		debug:         config.Debug,
+		debugUser:     strings.TrimSpace(config.DebugUser),
+		debugPassword: config.DebugPassword,
+
+		tlsAddr:        strings.TrimSpace(config.TLSAddr),
+		unixSocket:     strings.TrimSpace(config.UnixSocket),
+		unixSocketMode: os.FileMode(mode),
+		redirectAddr:   strings.TrimSpace(config.RedirectAddr),
 	}, nil
 }
 
@@ -57,3 +94,60 @@ func NewServer(opts ...Option) (Server, error) {
 func (s Server) Addr() string {
 	return s.addr
 }
+
+// AdminAddr returns the network address of the admin listener, serving
+// metrics. The value is set from the <PREFIX_>SERVER_ADMIN_ADDR environment
+// variable, and the admin listener is disabled when it is empty.
+func (s Server) AdminAddr() string {
+	return s.adminAddr
+}
+
+// Debug returns whether the debug routes, e.g. pprof, are served by the admin
+// listener. The value is set from the <PREFIX_>SERVER_DEBUG environment
+// variable.
+func (s Server) Debug() bool {
+	return s.debug
+}
+
+// DebugUser returns the user of the basic authentication of the debug routes.
+// The value is set from the <PREFIX_>SERVER_DEBUG_USER environment variable,
+// and the debug routes are not authenticated when it is empty.
+func (s Server) DebugUser() string {
+	return s.debugUser
+}
+
+// DebugPassword returns the password of the basic authentication of the debug
+// routes. The value is set from the <PREFIX_>SERVER_DEBUG_PASSWORD environment
+// variable.
+func (s Server) DebugPassword() string {
+	return s.debugPassword
+}
+
+// TLSAddr returns the network address of an additional TLS listener. The value
+// is set from the <PREFIX_>SERVER_TLS_ADDR environment variable, and the
+// listener is disabled when it is empty.
+func (s Server) TLSAddr() string {
+	return s.tlsAddr
+}
+
+// UnixSocket returns the path of an additional Unix domain socket listener.
+// The value is set from the <PREFIX_>SERVER_UNIX_SOCKET environment variable,
+// and the listener is disabled when it is empty.
+func (s Server) UnixSocket() string {
+	return s.unixSocket
+}
+
+// UnixSocketMode returns the permissions of the Unix domain socket. The value
+// is set from the <PREFIX_>SERVER_UNIX_SOCKET_MODE environment variable, in
+// octal, e.g. 0660.
+func (s Server) UnixSocketMode() os.FileMode {
+	return s.unixSocketMode
+}
+
+// RedirectAddr returns the network address of the listener redirecting plain
+// HTTP clients to the TLS listener. The value is set from the
+// <PREFIX_>SERVER_REDIRECT_ADDR environment variable, and the listener is
+// disabled when it is empty.
+func (s Server) RedirectAddr() string {
+	return s.redirectAddr
+}
@@ -30,6 +30,52 @@ func TestServer(t *testing.T) {
 		}
 	})
 
+	t.Run("with admin addr", func(t *testing.T) {
+		t.Setenv("SERVER_ADDR", "test_addr:42")
+		t.Setenv("SERVER_ADMIN_ADDR", " test_addr:9090 ")
+		cfg := setupServer(t)
+
+		if cfg.AdminAddr() != "test_addr:9090" {
+			t.Errorf("Unexpected admin addr: %s", cfg.AdminAddr())
+		}
+	})
+
+	t.Run("with debug", func(t *testing.T) {
+		t.Setenv("SERVER_ADDR", "test_addr:42")
+		t.Setenv("SERVER_DEBUG", "true")
+		t.Setenv("SERVER_DEBUG_USER", " admin ")
+		t.Setenv("SERVER_DEBUG_PASSWORD", "secret")
+		cfg := setupServer(t)
+
+		if !cfg.Debug() || cfg.DebugUser() != "admin" || cfg.DebugPassword() != "secret" {
+			t.Errorf("Unexpected debug config: %t %s %s", cfg.Debug(), cfg.DebugUser(), cfg.DebugPassword())
+		}
+	})
+
+	t.Run("with listeners", func(t *testing.T) {
+		t.Setenv("SERVER_ADDR", "test_addr:42")
+		t.Setenv("SERVER_TLS_ADDR", "test_addr:443")
+		t.Setenv("SERVER_UNIX_SOCKET", " /run/test.sock ")
+		t.Setenv("SERVER_UNIX_SOCKET_MODE", "0660")
+		t.Setenv("SERVER_REDIRECT_ADDR", "test_addr:80")
+		cfg := setupServer(t)
+
+		if cfg.TLSAddr() != "test_addr:443" || cfg.UnixSocket() != "/run/test.sock" ||
+			cfg.UnixSocketMode() != 0660 || cfg.RedirectAddr() != "test_addr:80" {
+			t.Errorf("Unexpected listeners config: %+v", cfg)
+		}
+	})
+
+	t.Run("with invalid unix socket mode", func(t *testing.T) {
+		t.Setenv("SERVER_ADDR", "test_addr:42")
+		t.Setenv("SERVER_UNIX_SOCKET_MODE", "rw-rw----")
+
+		_, err := config.NewServer()
+		if err == nil || err.Error() != "failed to load server configuration: invalid unix socket mode: rw-rw----" {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
 	t.Run("with prefix", func(t *testing.T) {
 		t.Setenv("FANCY_SERVER_ADDR", "test_addr:42")
 		cfg := setupServer(t, config.WithPrefix("fancy"))
@@ -16,6 +16,8 @@ package errors // import "arcadium.dev/core/errors
 
 import (
 	"errors"
+	"runtime"
+	"strings"
 )
 
 var (
@@ -25,3 +27,113 @@ var (
 	ErrInternal        = errors.New("internal error")
 	ErrNotImplemented  = errors.New("not implemented")
 )
+
+var (
+	sentinels = []error{
+		ErrInvalidArgument,
+		ErrNotFound,
+		ErrAlreadyExists,
+		ErrInternal,
+		ErrNotImplemented,
+	}
+)
+
+// Sentinel returns the error defined by this package found in err's chain, or
+// nil if there is none.
+func Sentinel(err error) error {
+	for _, sentinel := range sentinels {
+		if errors.Is(err, sentinel) {
+			return sentinel
+		}
+	}
+	return nil
+}
+
+// WithStack annotates err with the stack trace of the caller. It returns nil
+// if err is nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, stack: pcs[:n]}
+}
+
+// Join returns an error wrapping the given non-nil errors, e.g. the errors of
+// the steps of a shutdown. It returns nil if all of the errors are nil, and the
+// error itself if only one is not nil. The joined error matches each of the
+// errors with errors.Is and errors.As.
+func Join(errs ...error) error {
+	var joined []error
+	for _, err := range errs {
+		if err != nil {
+			joined = append(joined, err)
+		}
+	}
+	switch len(joined) {
+	case 0:
+		return nil
+	case 1:
+		return joined[0]
+	}
+	return &joinError{errs: joined}
+}
+
+type (
+	stackError struct {
+		err   error
+		stack []uintptr
+	}
+
+	joinError struct {
+		errs []error
+	}
+)
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+// StackTrace returns the program counters of the stack trace, see
+// runtime.CallersFrames.
+func (e *stackError) StackTrace() []uintptr {
+	return e.stack
+}
+
+func (e *joinError) Error() string {
+	msgs := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the joined errors.
+func (e *joinError) Unwrap() []error {
+	return e.errs
+}
+
+// Is reports whether any of the joined errors matches target.
+func (e *joinError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the joined errors that matches target.
+func (e *joinError) As(target interface{}) bool {
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors_test
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	cerrors "arcadium.dev/core/errors"
+)
+
+func TestSentinel(t *testing.T) {
+	err := fmt.Errorf("failed to load user: %w", cerrors.ErrNotFound)
+	if cerrors.Sentinel(err) != cerrors.ErrNotFound {
+		t.Errorf("Unexpected sentinel: %s", cerrors.Sentinel(err))
+	}
+	if cerrors.Sentinel(errors.New("unknown")) != nil {
+		t.Error("Expected no sentinel")
+	}
+}
+
+func TestWithStack(t *testing.T) {
+	if cerrors.WithStack(nil) != nil {
+		t.Error("Expected nil")
+	}
+
+	err := cerrors.WithStack(cerrors.ErrInternal)
+	if err.Error() != "internal error" || !errors.Is(err, cerrors.ErrInternal) {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	st, ok := err.(interface{ StackTrace() []uintptr })
+	if !ok {
+		t.Fatal("Expected a stack trace")
+	}
+	frame, _ := runtime.CallersFrames(st.StackTrace()).Next()
+	if !strings.HasSuffix(frame.Function, "TestWithStack") {
+		t.Errorf("Unexpected frame: %s", frame.Function)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if cerrors.Join(nil, nil) != nil {
+		t.Error("Expected nil")
+	}
+
+	err := errors.New("single")
+	if cerrors.Join(nil, err) != err {
+		t.Error("Expected the single error")
+	}
+
+	joined := cerrors.Join(fmt.Errorf("shutdown failed: %w", cerrors.ErrInternal), nil, cerrors.ErrNotFound)
+	if joined.Error() != "shutdown failed: internal error; not found" {
+		t.Errorf("Unexpected error: %s", joined)
+	}
+	if !errors.Is(joined, cerrors.ErrInternal) || !errors.Is(joined, cerrors.ErrNotFound) || errors.Is(joined, cerrors.ErrInvalidArgument) {
+		t.Errorf("Unexpected matches: %s", joined)
+	}
+}
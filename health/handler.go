@@ -0,0 +1,67 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health // import "arcadium.dev/core/health"
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LivezHandler returns a handler reporting the liveness checks. The response
+// status is 503 when a critical check fails. The results of the checks are
+// included when the verbose query parameter is given.
+func (h *Health) LivezHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write(w, h.Live(r.Context()), verbose(r))
+	})
+}
+
+// ReadyzHandler returns a handler reporting all of the checks. The response
+// status is 503 when a critical check fails, or when the service is shutting
+// down. The results of the checks are included when the verbose query
+// parameter is given.
+func (h *Health) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write(w, h.Ready(r.Context()), verbose(r))
+	})
+}
+
+// HealthzHandler returns a handler reporting all of the checks, always with
+// their results. The response status is as for ReadyzHandler.
+func (h *Health) HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write(w, h.Ready(r.Context()), true)
+	})
+}
+
+func verbose(r *http.Request) bool {
+	_, ok := r.URL.Query()["verbose"]
+	return ok
+}
+
+func write(w http.ResponseWriter, report Report, verbose bool) {
+	status := http.StatusOK
+	if report.Status == StatusFailing || report.Status == StatusShuttingDown {
+		status = http.StatusServiceUnavailable
+	}
+	if !verbose {
+		report.Checks = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,264 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health // import "arcadium.dev/core/health"
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+)
+
+// Status values of a check and of a report.
+const (
+	// StatusOK reports a passing check, or that all checks passed.
+	StatusOK Status = "ok"
+
+	// StatusDegraded reports that only non-critical checks failed.
+	StatusDegraded Status = "degraded"
+
+	// StatusFailing reports a failing check, or that a critical check failed.
+	StatusFailing Status = "failing"
+
+	// StatusShuttingDown reports that the server is shutting down, and is no
+	// longer ready.
+	StatusShuttingDown Status = "shutting_down"
+)
+
+type (
+	// Status is the status of a check or a report.
+	Status string
+
+	// Checker checks the health of a dependency or of a part of the service.
+	// A nil error reports a healthy check.
+	Checker interface {
+		Check(ctx context.Context) error
+	}
+
+	// CheckerFunc is an adapter allowing a function to be used as a Checker.
+	CheckerFunc func(ctx context.Context) error
+
+	// Pinger is implemented by *sql.DB.
+	Pinger interface {
+		PingContext(ctx context.Context) error
+	}
+
+	// Health holds the named checks of a service, and reports their results.
+	Health struct {
+		metrics      *metrics
+		shuttingDown int32
+
+		mu     sync.RWMutex
+		checks map[string]*check
+	}
+
+	// Report is the result of running the checks.
+	Report struct {
+		Status Status   `json:"status"`
+		Checks []Result `json:"checks,omitempty"`
+	}
+
+	// Result is the result of a single check.
+	Result struct {
+		Name     string `json:"name"`
+		Status   Status `json:"status"`
+		Critical bool   `json:"critical"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration"`
+		Cached   bool   `json:"cached,omitempty"`
+	}
+
+	check struct {
+		name     string
+		checker  Checker
+		timeout  time.Duration
+		ttl      time.Duration
+		critical bool
+		liveness bool
+
+		mu     sync.Mutex
+		result Result
+		at     time.Time
+	}
+)
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// PingChecker returns a checker which pings the database, or other pinger.
+func PingChecker(p Pinger) Checker {
+	return CheckerFunc(p.PingContext)
+}
+
+// New returns a Health without checks.
+func New(opts ...Option) (*Health, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+	m, err := newMetrics(o.registerer)
+	if err != nil {
+		return nil, err
+	}
+	return &Health{
+		metrics: m,
+		checks:  make(map[string]*check),
+	}, nil
+}
+
+// Register adds a check with the given name, replacing any check with the
+// same name. Checks are critical by default, are given a timeout of 5s, and
+// their results are not cached.
+func (h *Health) Register(name string, checker Checker, opts ...CheckOption) {
+	c := &check{
+		name:     name,
+		checker:  checker,
+		timeout:  defaultTimeout,
+		critical: true,
+	}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks[name] = c
+}
+
+// Shutdown marks the service as shutting down: readiness fails from then on.
+// It is called by the http server when it begins to shutdown.
+func (h *Health) Shutdown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+// ShuttingDown reports whether Shutdown has been called.
+func (h *Health) ShuttingDown() bool {
+	return atomic.LoadInt32(&h.shuttingDown) == 1
+}
+
+// Live runs the checks registered with the Liveness option.
+func (h *Health) Live(ctx context.Context) Report {
+	return h.run(ctx, func(c *check) bool { return c.liveness })
+}
+
+// Ready runs all of the checks. The report is failing when the service is
+// shutting down.
+func (h *Health) Ready(ctx context.Context) Report {
+	r := h.run(ctx, func(*check) bool { return true })
+	if h.ShuttingDown() {
+		r.Status = StatusShuttingDown
+	}
+	return r
+}
+
+// run runs the selected checks concurrently, and reports their results
+// ordered by name.
+func (h *Health) run(ctx context.Context, selected func(*check) bool) Report {
+	h.mu.RLock()
+	var checks []*check
+	for _, c := range h.checks {
+		if selected(c) {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, h.metrics)
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	r := Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		switch {
+		case result.Status == StatusOK:
+		case result.Critical:
+			r.Status = StatusFailing
+		case r.Status == StatusOK:
+			r.Status = StatusDegraded
+		}
+	}
+	return r
+}
+
+// run runs the check, unless its cached result is still valid.
+func (c *check) run(ctx context.Context, m *metrics) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl > 0 && !c.at.IsZero() && time.Since(c.at) < c.ttl {
+		r := c.result
+		r.Cached = true
+		return r
+	}
+
+	start := time.Now()
+	err := c.call(ctx)
+	d := time.Since(start)
+	r := Result{
+		Name:     c.name,
+		Status:   StatusOK,
+		Critical: c.critical,
+		Duration: d.String(),
+	}
+	if err != nil {
+		r.Status = StatusFailing
+		r.Error = err.Error()
+	}
+	m.observe(r, d)
+
+	c.result, c.at = r, start
+	return r
+}
+
+// call calls the checker, abandoning it when the timeout expires.
+func (c *check) call(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		result <- c.checker.Check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out after %s: %w", c.timeout, ctx.Err())
+	}
+}
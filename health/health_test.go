@@ -0,0 +1,252 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"arcadium.dev/core/health"
+)
+
+func pass(context.Context) error { return nil }
+
+func fail(context.Context) error { return errors.New("check failed") }
+
+func newHealth(t *testing.T, opts ...health.Option) *health.Health {
+	t.Helper()
+	h, err := health.New(opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return h
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks map[string]health.CheckerFunc
+		opts   []health.CheckOption
+		status health.Status
+	}{
+		{
+			name:   "no checks",
+			status: health.StatusOK,
+		},
+		{
+			name:   "passing",
+			checks: map[string]health.CheckerFunc{"a": pass, "b": pass},
+			status: health.StatusOK,
+		},
+		{
+			name:   "critical failure",
+			checks: map[string]health.CheckerFunc{"a": pass, "b": fail},
+			status: health.StatusFailing,
+		},
+		{
+			name:   "non-critical failure",
+			checks: map[string]health.CheckerFunc{"a": pass, "b": fail},
+			opts:   []health.CheckOption{health.NonCritical()},
+			status: health.StatusDegraded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newHealth(t)
+			for name, check := range test.checks {
+				h.Register(name, check, test.opts...)
+			}
+
+			r := h.Ready(context.Background())
+			if r.Status != test.status {
+				t.Errorf("\nExpected status: %s\nActual status:   %s", test.status, r.Status)
+			}
+			if len(r.Checks) != len(test.checks) {
+				t.Fatalf("Unexpected checks: %+v", r.Checks)
+			}
+			for i := 1; i < len(r.Checks); i++ {
+				if r.Checks[i-1].Name > r.Checks[i].Name {
+					t.Errorf("Unexpected order: %+v", r.Checks)
+				}
+			}
+		})
+	}
+}
+
+func TestLive(t *testing.T) {
+	h := newHealth(t)
+	h.Register("dependency", health.CheckerFunc(fail))
+	h.Register("deadlock", health.CheckerFunc(pass), health.Liveness())
+
+	r := h.Live(context.Background())
+	if r.Status != health.StatusOK || len(r.Checks) != 1 || r.Checks[0].Name != "deadlock" {
+		t.Errorf("Unexpected report: %+v", r)
+	}
+
+	h.Shutdown()
+
+	if r := h.Live(context.Background()); r.Status != health.StatusOK {
+		t.Errorf("\nExpected status: %s\nActual status:   %s", health.StatusOK, r.Status)
+	}
+	if r := h.Ready(context.Background()); r.Status != health.StatusShuttingDown {
+		t.Errorf("\nExpected status: %s\nActual status:   %s", health.StatusShuttingDown, r.Status)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	h := newHealth(t)
+	h.Register("slow", health.CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), health.WithTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	r := h.Ready(context.Background())
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Expected the check to be abandoned")
+	}
+	if r.Status != health.StatusFailing || !strings.Contains(r.Checks[0].Error, "check timed out after 10ms") {
+		t.Errorf("Unexpected report: %+v", r)
+	}
+}
+
+func TestCache(t *testing.T) {
+	var calls int32
+	h := newHealth(t)
+	h.Register("cached", health.CheckerFunc(func(context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), health.WithCache(time.Minute))
+
+	first := h.Ready(context.Background())
+	second := h.Ready(context.Background())
+
+	if calls != 1 {
+		t.Errorf("\nExpected calls: 1\nActual calls:   %d", calls)
+	}
+	if first.Checks[0].Cached || !second.Checks[0].Cached {
+		t.Errorf("Unexpected results: %+v %+v", first.Checks[0], second.Checks[0])
+	}
+}
+
+func TestPingChecker(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	check := health.PingChecker(db)
+	if err := check.Check(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := check.Check(context.Background()); err == nil || err.Error() != "connection refused" {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := newHealth(t, health.WithMetrics(reg))
+	h.Register("a", health.CheckerFunc(pass))
+	h.Register("b", health.CheckerFunc(fail))
+	h.Ready(context.Background())
+
+	// A second Health shares the collectors.
+	newHealth(t, health.WithMetrics(reg))
+
+	expected := `
+# HELP health_check_failures_total Total number of failures of each check.
+# TYPE health_check_failures_total counter
+health_check_failures_total{check="b"} 1
+# HELP health_check_status The status of the last run of each check, 1 when passing.
+# TYPE health_check_status gauge
+health_check_status{check="a"} 1
+health_check_status{check="b"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "health_check_failures_total", "health_check_status"); err != nil {
+		t.Errorf("Unexpected metrics: %s", err)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	h := newHealth(t)
+	h.Register("a", health.CheckerFunc(pass), health.Liveness())
+	h.Register("b", health.CheckerFunc(fail), health.NonCritical())
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		target  string
+		status  int
+		checks  int
+	}{
+		{name: "livez", handler: h.LivezHandler(), target: "/livez", status: http.StatusOK},
+		{name: "livez verbose", handler: h.LivezHandler(), target: "/livez?verbose", status: http.StatusOK, checks: 1},
+		{name: "readyz", handler: h.ReadyzHandler(), target: "/readyz", status: http.StatusOK},
+		{name: "readyz verbose", handler: h.ReadyzHandler(), target: "/readyz?verbose", status: http.StatusOK, checks: 2},
+		{name: "healthz", handler: h.HealthzHandler(), target: "/healthz", status: http.StatusOK, checks: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			test.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.target, nil))
+
+			if w.Code != test.status {
+				t.Errorf("\nExpected status: %d\nActual status:   %d", test.status, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Unexpected content type: %s", ct)
+			}
+			var r health.Report
+			if err := json.NewDecoder(w.Body).Decode(&r); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if len(r.Checks) != test.checks {
+				t.Errorf("\nExpected checks: %d\nActual checks:   %d", test.checks, len(r.Checks))
+			}
+		})
+	}
+
+	t.Run("shutting down", func(t *testing.T) {
+		h.Shutdown()
+
+		w := httptest.NewRecorder()
+		h.ReadyzHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("\nExpected status: %d\nActual status:   %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `"status":"shutting_down"`) {
+			t.Errorf("Unexpected body: %s", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health // import "arcadium.dev/core/health"
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"arcadium.dev/core/internal/prom"
+)
+
+type (
+	// metrics holds the collectors of the check results.
+	metrics struct {
+		status   *prometheus.GaugeVec
+		duration *prometheus.HistogramVec
+		failures *prometheus.CounterVec
+	}
+)
+
+// newMetrics creates the collectors and registers them with the registerer.
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	if reg == nil {
+		return nil, nil
+	}
+	m := &metrics{
+		status: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "health_check_status",
+			Help: "The status of the last run of each check, 1 when passing.",
+		}, []string{"check"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "health_check_duration_seconds",
+			Help: "The time taken by each check, in seconds.",
+		}, []string{"check"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "health_check_failures_total",
+			Help: "Total number of failures of each check.",
+		}, []string{"check"}),
+	}
+
+	var err error
+	register := func(c prometheus.Collector) prometheus.Collector {
+		c, rerr := prom.Register(reg, c)
+		if err == nil && rerr != nil {
+			err = fmt.Errorf("failed to register health metrics: %w", rerr)
+		}
+		return c
+	}
+	m.status = register(m.status).(*prometheus.GaugeVec)
+	m.duration = register(m.duration).(*prometheus.HistogramVec)
+	m.failures = register(m.failures).(*prometheus.CounterVec)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// observe records the result of a check.
+func (m *metrics) observe(r Result, d time.Duration) {
+	if m == nil {
+		return
+	}
+	status := 0.0
+	if r.Status == StatusOK {
+		status = 1
+	} else {
+		m.failures.WithLabelValues(r.Name).Inc()
+	}
+	m.status.WithLabelValues(r.Name).Set(status)
+	m.duration.WithLabelValues(r.Name).Observe(d.Seconds())
+}
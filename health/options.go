@@ -0,0 +1,104 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health // import "arcadium.dev/core/health"
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// Option provides for Health configuration.
+	Option interface {
+		apply(*options)
+	}
+
+	// CheckOption provides for the configuration of a check, see Register.
+	CheckOption interface {
+		apply(*check)
+	}
+)
+
+// WithMetrics records the status, duration and failures of each check in
+// metrics registered with the given registerer.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return newOption(func(opts *options) {
+		opts.registerer = reg
+	})
+}
+
+// WithTimeout sets the time the check may take before it fails. The default is
+// 5s.
+func WithTimeout(timeout time.Duration) CheckOption {
+	return newCheckOption(func(c *check) {
+		c.timeout = timeout
+	})
+}
+
+// WithCache caches the result of the check for the given duration, limiting
+// the load put on the dependency by frequent probes.
+func WithCache(ttl time.Duration) CheckOption {
+	return newCheckOption(func(c *check) {
+		c.ttl = ttl
+	})
+}
+
+// NonCritical classifies the check as non-critical: its failure degrades the
+// report, but does not fail it.
+func NonCritical() CheckOption {
+	return newCheckOption(func(c *check) {
+		c.critical = false
+	})
+}
+
+// Liveness includes the check in the liveness report. Liveness checks should
+// only fail when the service must be restarted, they should not check
+// dependencies.
+func Liveness() CheckOption {
+	return newCheckOption(func(c *check) {
+		c.liveness = true
+	})
+}
+
+type (
+	options struct {
+		registerer prometheus.Registerer
+	}
+
+	option struct {
+		f func(*options)
+	}
+
+	checkOption struct {
+		f func(*check)
+	}
+)
+
+func newOption(f func(*options)) option {
+	return option{f: f}
+}
+
+func (o option) apply(opts *options) {
+	o.f(opts)
+}
+
+func newCheckOption(f func(*check)) checkOption {
+	return checkOption{f: f}
+}
+
+func (o checkOption) apply(c *check) {
+	o.f(c)
+}
@@ -0,0 +1,201 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"arcadium.dev/core/log"
+)
+
+// Fields of an access log entry.
+const (
+	AccessLogMethod    AccessLogField = "method"
+	AccessLogPath      AccessLogField = "path"
+	AccessLogProto     AccessLogField = "proto"
+	AccessLogStatus    AccessLogField = "status"
+	AccessLogSize      AccessLogField = "size"
+	AccessLogDuration  AccessLogField = "duration"
+	AccessLogRemote    AccessLogField = "remote"
+	AccessLogUserAgent AccessLogField = "user_agent"
+	AccessLogTLS       AccessLogField = "tls"
+	AccessLogClient    AccessLogField = "client"
+)
+
+var (
+	// defaultAccessLogFields are the fields logged by default, in order.
+	defaultAccessLogFields = []AccessLogField{
+		AccessLogMethod,
+		AccessLogPath,
+		AccessLogProto,
+		AccessLogStatus,
+		AccessLogSize,
+		AccessLogDuration,
+		AccessLogRemote,
+		AccessLogUserAgent,
+		AccessLogTLS,
+		AccessLogClient,
+	}
+
+	tlsVersions = map[uint16]string{
+		tls.VersionTLS10: "1.0",
+		tls.VersionTLS11: "1.1",
+		tls.VersionTLS12: "1.2",
+		tls.VersionTLS13: "1.3",
+	}
+)
+
+type (
+	// AccessLogField is the key of a field of an access log entry.
+	AccessLogField string
+
+	// AccessLogOption provides options for configuring the AccessLog
+	// middleware.
+	AccessLogOption interface {
+		apply(*accessLogOptions)
+	}
+
+	accessLogOptions struct {
+		fields  []AccessLogField
+		exclude map[string]bool
+	}
+)
+
+// AccessLog is middleware which logs each request, once handled, at the info
+// level. The entry is logged with the values extracted from the request's
+// context, e.g. the request ID when following the RequestID middleware. The
+// tls field holds the TLS version, and the client field the subject of the
+// verified client certificate, when mTLS is used.
+func AccessLog(logger log.Logger, opts ...AccessLogOption) mux.MiddlewareFunc {
+	o := accessLogOptions{
+		fields:  defaultAccessLogFields,
+		exclude: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.excluded(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw := wrapResponseWriter(w)
+			start := time.Now()
+			next.ServeHTTP(rw, r)
+			duration := time.Since(start)
+
+			kv := make([]interface{}, 0, 2+2*len(o.fields))
+			kv = append(kv, "msg", "request")
+			for _, f := range o.fields {
+				if v, ok := accessLogValue(f, r, rw, duration); ok {
+					kv = append(kv, string(f), v)
+				}
+			}
+			logger.InfoContext(r.Context(), kv...)
+		})
+	}
+}
+
+// accessLogValue returns the value of the field, and whether it applies to
+// the request.
+func accessLogValue(f AccessLogField, r *http.Request, rw *responseWriter, d time.Duration) (interface{}, bool) {
+	switch f {
+	case AccessLogMethod:
+		return r.Method, true
+	case AccessLogPath:
+		return r.URL.RequestURI(), true
+	case AccessLogProto:
+		return r.Proto, true
+	case AccessLogStatus:
+		// The handler wrote nothing: net/http responds 200.
+		if rw.Status() == 0 {
+			return http.StatusOK, true
+		}
+		return rw.Status(), true
+	case AccessLogSize:
+		return rw.Size(), true
+	case AccessLogDuration:
+		return d, true
+	case AccessLogRemote:
+		return r.RemoteAddr, true
+	case AccessLogUserAgent:
+		return r.UserAgent(), r.UserAgent() != ""
+	case AccessLogTLS:
+		if r.TLS == nil {
+			return nil, false
+		}
+		return tlsVersions[r.TLS.Version], true
+	case AccessLogClient:
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return nil, false
+		}
+		return r.TLS.VerifiedChains[0][0].Subject.String(), true
+	}
+	return nil, false
+}
+
+// excluded reports whether the path matches one of the exclusions.
+func (o accessLogOptions) excluded(path string) bool {
+	if o.exclude[path] {
+		return true
+	}
+	for p := range o.exclude {
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(path, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAccessLogFields selects the fields logged, in the given order. By
+// default all fields are logged.
+func WithAccessLogFields(fields ...AccessLogField) AccessLogOption {
+	return newAccessLogOption(func(o *accessLogOptions) {
+		o.fields = fields
+	})
+}
+
+// WithAccessLogExclude excludes requests for the given paths, e.g. health
+// checks, from the access log. A path ending with a "*" excludes all paths
+// with the preceding prefix.
+func WithAccessLogExclude(paths ...string) AccessLogOption {
+	return newAccessLogOption(func(o *accessLogOptions) {
+		for _, p := range paths {
+			o.exclude[p] = true
+		}
+	})
+}
+
+type (
+	accessLogOption struct {
+		f func(*accessLogOptions)
+	}
+)
+
+func newAccessLogOption(f func(*accessLogOptions)) accessLogOption {
+	return accessLogOption{f: f}
+}
+
+func (o accessLogOption) apply(opts *accessLogOptions) {
+	o.f(opts)
+}
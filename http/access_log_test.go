@@ -0,0 +1,186 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"arcadium.dev/core/log"
+)
+
+func TestAccessLog(t *testing.T) {
+	setup := func(t *testing.T, opts ...AccessLogOption) (http.Handler, *log.StringBuffer) {
+		t.Helper()
+
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		h := RequestID()(AccessLog(l, opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("hello"))
+		})))
+		return h, b
+	}
+
+	t.Run("all fields", func(t *testing.T) {
+		h, b := setup(t)
+
+		r := httptest.NewRequest(http.MethodPost, "/foo?bar=1", nil)
+		r.Header.Set("User-Agent", "test/1.0")
+		r.Header.Set(RequestIDHeader, "req-1")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		if b.Len() != 1 {
+			t.Fatalf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := regexp.MustCompile(`^level=info msg=request method=POST path="/foo\?bar=1" proto=HTTP/1.1 ` +
+			`status=201 size=5 duration=\S+ remote=192.0.2.1:1234 user_agent=test/1.0 request_id=req-1\n$`)
+		if !expected.MatchString(b.Index(0)) {
+			t.Errorf("Unexpected log: %s", b.Index(0))
+		}
+	})
+
+	t.Run("selected fields", func(t *testing.T) {
+		h, b := setup(t, WithAccessLogFields(AccessLogStatus, AccessLogMethod))
+
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set(RequestIDHeader, "req-1")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		expected := "level=info msg=request status=201 method=GET request_id=req-1\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+	})
+
+	t.Run("empty response", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		h := AccessLog(l, WithAccessLogFields(AccessLogStatus, AccessLogSize))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/foo", nil))
+
+		expected := "level=info msg=request status=200 size=0\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+	})
+
+	t.Run("excluded paths", func(t *testing.T) {
+		h, b := setup(t, WithAccessLogExclude("/healthz", "/debug/*"))
+
+		for _, path := range []string{"/healthz", "/debug/pprof/", "/foo"} {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+		if b.Len() != 1 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		srv := httptest.NewTLSServer(AccessLog(l, WithAccessLogFields(AccessLogTLS, AccessLogClient))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		))
+		defer srv.Close()
+
+		resp, err := srv.Client().Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		resp.Body.Close()
+
+		expected := "level=info msg=request tls=1.3\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+	})
+}
+
+func TestResponseWriter(t *testing.T) {
+	t.Run("status and size", func(t *testing.T) {
+		rw := wrapResponseWriter(httptest.NewRecorder())
+		if rw.Status() != 0 {
+			t.Errorf("Unexpected status: %d", rw.Status())
+		}
+		rw.Write([]byte("hello"))
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(" world"))
+		if rw.Status() != http.StatusOK || rw.Size() != 11 {
+			t.Errorf("Unexpected status %d, size %d", rw.Status(), rw.Size())
+		}
+		if wrapResponseWriter(rw) != rw {
+			t.Error("Expected the wrapper to be reused")
+		}
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rw := wrapResponseWriter(w)
+		rw.Flush()
+		if !w.Flushed || rw.Status() != http.StatusOK {
+			t.Error("Expected the recorder to be flushed")
+		}
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := wrapResponseWriter(w)
+			conn, buf, err := rw.Hijack()
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 204 No Content\r\n\r\n")
+			buf.Flush()
+			if !rw.hijacked || rw.Status() != http.StatusSwitchingProtocols {
+				t.Errorf("Unexpected status: %d", rw.Status())
+			}
+		}))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("Unexpected status: %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		rw := wrapResponseWriter(httptest.NewRecorder())
+		if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if err := rw.Push("/style.css", nil); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"arcadium.dev/core/build"
+	"arcadium.dev/core/internal/prom"
+)
+
+type (
+	// BuildInfoOption provides options for configuring the build information
+	// handler.
+	BuildInfoOption interface {
+		apply(*buildInfoOptions)
+	}
+
+	buildInfoOptions struct {
+		details bool
+	}
+)
+
+// BuildInfoHandler returns a handler which serves the build information as
+// JSON. The dependencies and the build settings are left out, unless the
+// WithBuildInfoDetails option is given.
+func BuildInfoHandler(info build.Information, opts ...BuildInfoOption) http.Handler {
+	o := buildInfoOptions{}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+	if !o.details {
+		info.Deps, info.Settings = nil, nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(info)
+	})
+}
+
+// registerBuildInfo registers the build_info gauge, set to 1 and labelled with
+// the build information, with the registerer given by the options, by default
+// the prometheus default registerer.
+func registerBuildInfo(info build.Information, o metricsOptions) error {
+	reg := o.registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: o.namespace,
+		Subsystem: o.subsystem,
+		Name:      "build_info",
+		Help:      "The build information of the server, the value is always 1.",
+	}, []string{"name", "version", "branch", "commit", "go_version"})
+
+	c, err := prom.Register(reg, g)
+	if err != nil {
+		return fmt.Errorf("failed to register http metrics: %w", err)
+	}
+	c.(*prometheus.GaugeVec).WithLabelValues(info.Name, info.Version, info.Branch, info.Commit, info.Go).Set(1)
+	return nil
+}
+
+// WithBuildInfoDetails also serves the dependencies and the build settings,
+// e.g. -ldflags, which should only be exposed on a private listener.
+func WithBuildInfoDetails() BuildInfoOption {
+	return newBuildInfoOption(func(o *buildInfoOptions) {
+		o.details = true
+	})
+}
+
+type (
+	buildInfoOption struct {
+		f func(*buildInfoOptions)
+	}
+)
+
+func newBuildInfoOption(f func(*buildInfoOptions)) buildInfoOption {
+	return buildInfoOption{f: f}
+}
+
+func (o buildInfoOption) apply(opts *buildInfoOptions) {
+	o.f(opts)
+}
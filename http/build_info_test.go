@@ -0,0 +1,104 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"arcadium.dev/core/build"
+)
+
+func TestBuildInfo(t *testing.T) {
+	info := build.Information{
+		Name:    "name",
+		Version: "v1.2.3",
+		Branch:  "main",
+		Commit:  "abc123",
+		Date:    "2022-01-01",
+		Go:      "go1.18",
+	}
+
+	t.Run("handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		BuildInfoHandler(info).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buildinfo", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("\nExpected status: %d\nActual status:   %d", http.StatusOK, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Unexpected content type: %s", ct)
+		}
+		var actual build.Information
+		if err := json.NewDecoder(w.Body).Decode(&actual); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(actual, info) {
+			t.Errorf("\nExpected info: %+v\nActual info:   %+v", info, actual)
+		}
+	})
+
+	t.Run("details", func(t *testing.T) {
+		detailed := info
+		detailed.Deps = []build.Module{{Path: "github.com/gorilla/mux", Version: "v1.8.0"}}
+		detailed.Settings = map[string]string{"-ldflags": "-X main.secret=s3cr3t"}
+
+		w := httptest.NewRecorder()
+		BuildInfoHandler(detailed).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buildinfo", nil))
+		if body := w.Body.String(); strings.Contains(body, "deps") || strings.Contains(body, "s3cr3t") {
+			t.Errorf("Unexpected details: %s", body)
+		}
+
+		w = httptest.NewRecorder()
+		BuildInfoHandler(detailed, WithBuildInfoDetails()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buildinfo", nil))
+		var actual build.Information
+		if err := json.NewDecoder(w.Body).Decode(&actual); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(actual, detailed) {
+			t.Errorf("\nExpected info: %+v\nActual info:   %+v", detailed, actual)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		s := NewServer(
+			WithServerMetrics(WithMetricsRegisterer(reg)),
+			WithServerBuildInfo(info),
+		)
+
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buildinfo", nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"version":"v1.2.3"`) {
+			t.Errorf("Unexpected response: %d %s", w.Code, w.Body.String())
+		}
+
+		expected := `
+# HELP build_info The build information of the server, the value is always 1.
+# TYPE build_info gauge
+build_info{branch="main",commit="abc123",go_version="go1.18",name="name",version="v1.2.3"} 1
+`
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "build_info"); err != nil {
+			t.Errorf("Unexpected metrics: %s", err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"arcadium.dev/core/build"
+	cerrors "arcadium.dev/core/errors"
+)
+
+const (
+	// ClientVersionHeader is the request header carrying the client version.
+	ClientVersionHeader = "X-Client-Version"
+
+	// ServerVersionHeader is the response header carrying the server version.
+	ServerVersionHeader = "X-Server-Version"
+)
+
+type (
+	// CompatibilityOption provides options for configuring the Compatibility
+	// middleware.
+	CompatibilityOption interface {
+		apply(*compatibilityOptions)
+	}
+
+	compatibilityOptions struct {
+		required bool
+	}
+)
+
+// Compatibility is middleware which gates the clients by version. The server
+// version is set in the X-Server-Version response header, and requests whose
+// X-Client-Version header does not satisfy the constraint are refused with a
+// 400 response. Requests without the header are accepted, unless the
+// WithClientVersionRequired option is given.
+func Compatibility(version build.Semver, constraint build.Constraint, opts ...CompatibilityOption) mux.MiddlewareFunc {
+	o := compatibilityOptions{}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(ServerVersionHeader, version.String())
+
+			header := r.Header.Get(ClientVersionHeader)
+			if header == "" {
+				if o.required {
+					Response(r.Context(), w, fmt.Errorf("%w: missing %s header", cerrors.ErrInvalidArgument, ClientVersionHeader))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			v, err := build.ParseSemver(header)
+			if err != nil {
+				Response(r.Context(), w, fmt.Errorf("invalid %s header: %w", ClientVersionHeader, err))
+				return
+			}
+			if !constraint.Check(v) {
+				Response(r.Context(), w, fmt.Errorf("%w: client version %s is not compatible, %s is required",
+					cerrors.ErrInvalidArgument, v, constraint,
+				))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithClientVersionRequired refuses the requests without a client version.
+func WithClientVersionRequired() CompatibilityOption {
+	return newCompatibilityOption(func(o *compatibilityOptions) {
+		o.required = true
+	})
+}
+
+type (
+	compatibilityOption struct {
+		f func(*compatibilityOptions)
+	}
+)
+
+func newCompatibilityOption(f func(*compatibilityOptions)) compatibilityOption {
+	return compatibilityOption{f: f}
+}
+
+func (o compatibilityOption) apply(opts *compatibilityOptions) {
+	o.f(opts)
+}
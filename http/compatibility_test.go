@@ -0,0 +1,71 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"arcadium.dev/core/build"
+)
+
+func TestCompatibility(t *testing.T) {
+	version, err := build.ParseSemver("v2.3.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	constraint := build.MustParseConstraint(">=1.4, <3")
+
+	tests := []struct {
+		name   string
+		client string
+		opts   []CompatibilityOption
+		status int
+		body   string
+	}{
+		{name: "compatible", client: "1.4.0", status: http.StatusOK},
+		{name: "incompatible", client: "1.3.0", status: http.StatusBadRequest, body: "client version v1.3.0 is not compatible"},
+		{name: "invalid", client: "latest", status: http.StatusBadRequest, body: "invalid X-Client-Version header"},
+		{name: "missing", status: http.StatusOK},
+		{name: "missing required", opts: []CompatibilityOption{WithClientVersionRequired()}, status: http.StatusBadRequest, body: "missing X-Client-Version header"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := Compatibility(version, constraint, test.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.client != "" {
+				r.Header.Set(ClientVersionHeader, test.client)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("\nExpected status: %d\nActual status:   %d", test.status, w.Code)
+			}
+			if v := w.Header().Get(ServerVersionHeader); v != "v2.3.0" {
+				t.Errorf("Unexpected server version: %s", v)
+			}
+			if !strings.Contains(w.Body.String(), test.body) {
+				t.Errorf("\nExpected body: %s\nActual body:   %s", test.body, w.Body.String())
+			}
+		})
+	}
+}
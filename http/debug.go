@@ -0,0 +1,171 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxRecentPauses limits the garbage collector pauses reported by
+	// /debug/gc, most recent first.
+	maxRecentPauses = 16
+)
+
+type (
+	// DebugOption provides options for configuring the debug routes, see
+	// WithServerDebug.
+	DebugOption interface {
+		apply(*debugOptions)
+	}
+
+	debugOptions struct {
+		authorize func(*http.Request) bool
+	}
+
+	// gcStats is the response of the /debug/gc route.
+	gcStats struct {
+		NumGC         int64     `json:"num_gc"`
+		LastGC        time.Time `json:"last_gc"`
+		PauseTotal    string    `json:"pause_total"`
+		RecentPauses  []string  `json:"recent_pauses"`
+		HeapAlloc     uint64    `json:"heap_alloc"`
+		HeapSys       uint64    `json:"heap_sys"`
+		HeapObjects   uint64    `json:"heap_objects"`
+		NextGC        uint64    `json:"next_gc"`
+		GCCPUFraction float64   `json:"gc_cpu_fraction"`
+		Goroutines    int       `json:"goroutines"`
+		GOMAXPROCS    int       `json:"gomaxprocs"`
+	}
+)
+
+// registerDebug mounts the debug routes on the router:
+//
+//	/debug/pprof/       the net/http/pprof profiles
+//	/debug/goroutines   a dump of the goroutine stacks
+//	/debug/gc           the garbage collector and memory statistics, as JSON
+//	/debug/vars         the expvar variables
+func registerDebug(router *mux.Router, o debugOptions) {
+	r := router.PathPrefix("/debug").Subrouter()
+	if o.authorize != nil {
+		r.Use(authorizeDebug(o.authorize))
+	}
+
+	r.HandleFunc("/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/pprof/profile", pprof.Profile)
+	r.HandleFunc("/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/pprof/trace", pprof.Trace)
+	r.PathPrefix("/pprof/").HandlerFunc(pprof.Index)
+
+	r.HandleFunc("/goroutines", goroutines).Methods(http.MethodGet)
+	r.HandleFunc("/gc", gc).Methods(http.MethodGet)
+	r.Handle("/vars", expvar.Handler()).Methods(http.MethodGet)
+}
+
+// authorizeDebug is middleware refusing the unauthorized debug requests.
+func authorizeDebug(authorize func(*http.Request) bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !authorize(r) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="debug"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// goroutines writes the stacks of all goroutines.
+func goroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// gc writes the garbage collector and memory statistics.
+func gc(w http.ResponseWriter, r *http.Request) {
+	var (
+		stats debug.GCStats
+		mem   runtime.MemStats
+	)
+	debug.ReadGCStats(&stats)
+	runtime.ReadMemStats(&mem)
+
+	resp := gcStats{
+		NumGC:         stats.NumGC,
+		LastGC:        stats.LastGC,
+		PauseTotal:    stats.PauseTotal.String(),
+		RecentPauses:  []string{},
+		HeapAlloc:     mem.HeapAlloc,
+		HeapSys:       mem.HeapSys,
+		HeapObjects:   mem.HeapObjects,
+		NextGC:        mem.NextGC,
+		GCCPUFraction: mem.GCCPUFraction,
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+	}
+	for i, p := range stats.Pause {
+		if i == maxRecentPauses {
+			break
+		}
+		resp.RecentPauses = append(resp.RecentPauses, p.String())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// WithDebugAuth authorizes the debug requests with the given function. The
+// unauthorized requests are refused with a 401 response.
+func WithDebugAuth(authorize func(r *http.Request) bool) DebugOption {
+	return newDebugOption(func(o *debugOptions) {
+		o.authorize = authorize
+	})
+}
+
+// WithDebugBasicAuth authorizes the debug requests with basic authentication
+// with the given user and password.
+func WithDebugBasicAuth(user, password string) DebugOption {
+	return WithDebugAuth(func(r *http.Request) bool {
+		u, p, ok := r.BasicAuth()
+		return ok &&
+			subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+	})
+}
+
+type (
+	debugOption struct {
+		f func(*debugOptions)
+	}
+)
+
+func newDebugOption(f func(*debugOptions)) debugOption {
+	return debugOption{f: f}
+}
+
+func (o debugOption) apply(opts *debugOptions) {
+	o.f(opts)
+}
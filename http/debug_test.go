@@ -0,0 +1,90 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerDebug(t *testing.T) {
+	get := func(h http.Handler, path string, auth ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if len(auth) == 2 {
+			r.SetBasicAuth(auth[0], auth[1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("routes", func(t *testing.T) {
+		s := NewServer(WithServerAdminAddr("127.0.0.1:0"), WithServerDebug())
+
+		tests := []struct {
+			path string
+			body string
+		}{
+			{path: "/debug/pprof/", body: "goroutine"},
+			{path: "/debug/pprof/heap?debug=1", body: "heap profile"},
+			{path: "/debug/goroutines", body: "goroutine "},
+			{path: "/debug/gc", body: `"num_gc"`},
+			{path: "/debug/vars", body: `"memstats"`},
+		}
+		for _, test := range tests {
+			w := get(s.adminRouter, test.path)
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), test.body) {
+				t.Errorf("%s: Unexpected response: %d %.200s", test.path, w.Code, w.Body.String())
+			}
+
+			// The debug routes are never served alongside the services.
+			if w := get(s.server.Handler, test.path); w.Code != http.StatusNotFound {
+				t.Errorf("%s: Unexpected status: %d", test.path, w.Code)
+			}
+		}
+	})
+
+	t.Run("basic auth", func(t *testing.T) {
+		s := NewServer(WithServerAdminAddr("127.0.0.1:0"), WithServerDebug(WithDebugBasicAuth("admin", "secret")))
+
+		if w := get(s.adminRouter, "/debug/gc"); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Unexpected response: %d %s", w.Code, w.Body.String())
+		}
+		if w := get(s.adminRouter, "/debug/gc", "admin", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Errorf("Unexpected status: %d", w.Code)
+		}
+		if w := get(s.adminRouter, "/debug/gc", "admin", "secret"); w.Code != http.StatusOK {
+			t.Errorf("Unexpected status: %d", w.Code)
+		}
+		// Metrics are not affected.
+		if w := get(s.adminRouter, "/metrics"); w.Code != http.StatusOK {
+			t.Errorf("Unexpected status: %d", w.Code)
+		}
+	})
+
+	t.Run("without admin listener", func(t *testing.T) {
+		b, logger := setupLogger(t)
+		s := NewServer(WithServerLogger(logger), WithServerDebug())
+
+		if w := get(s.server.Handler, "/debug/gc"); w.Code != http.StatusNotFound {
+			t.Errorf("Unexpected status: %d", w.Code)
+		}
+		if b.Len() != 2 || !strings.Contains(b.Index(1), "debug routes require the admin listener") {
+			t.Errorf("Unexpected log: %d %s", b.Len(), b.Index(b.Len()-1))
+		}
+	})
+}
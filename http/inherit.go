@@ -0,0 +1,320 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The environment variables of systemd socket activation, see
+	// sd_listen_fds(3). They are also used to pass the listeners to the new
+	// process of a restart.
+	listenPIDEnv     = "LISTEN_PID"
+	listenFDsEnv     = "LISTEN_FDS"
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+
+	// listenReadyFDEnv holds the file descriptor on which the new process of
+	// a restart signals its readiness.
+	listenReadyFDEnv = "LISTEN_READY_FD"
+
+	// notifySocketEnv holds the socket of the systemd notifications, see
+	// sd_notify(3).
+	notifySocketEnv = "NOTIFY_SOCKET"
+
+	// listenFDsStart is the first inherited file descriptor.
+	listenFDsStart = 3
+
+	// defaultRestartTimeout bounds the wait for the readiness of the new
+	// process of a restart.
+	defaultRestartTimeout = 30 * time.Second
+)
+
+type (
+	// inheritedListener is a listener inherited from the parent process.
+	inheritedListener struct {
+		name     string
+		listener net.Listener
+	}
+
+	// openListener is a listener opened by the server, before any TLS
+	// wrapping.
+	openListener struct {
+		name     string
+		listener net.Listener
+	}
+
+	// filer is implemented by the TCP and Unix listeners.
+	filer interface {
+		File() (*os.File, error)
+	}
+)
+
+// inheritListeners returns the listeners passed by systemd socket activation,
+// or by the parent process of a restart. The environment variables are unset,
+// so they are not passed on to child processes.
+func inheritListeners() ([]inheritedListener, error) {
+	defer func() {
+		os.Unsetenv(listenPIDEnv)
+		os.Unsetenv(listenFDsEnv)
+		os.Unsetenv(listenFDNamesEnv)
+	}()
+
+	fds := os.Getenv(listenFDsEnv)
+	if fds == "" {
+		return nil, nil
+	}
+	// The listeners are intended for another process.
+	if pid := os.Getenv(listenPIDEnv); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s: %s", listenFDsEnv, fds)
+	}
+	var names []string
+	if v := os.Getenv(listenFDNamesEnv); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	listeners := make([]inheritedListener, 0, n)
+	for i := 0; i < n; i++ {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+
+		// The file is closed once duplicated by FileListener.
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, il := range listeners {
+				il.listener.Close()
+			}
+			return nil, fmt.Errorf("failed to inherit listener %d: %w", listenFDsStart+i, err)
+		}
+		listeners = append(listeners, inheritedListener{name: name, listener: l})
+	}
+	return listeners, nil
+}
+
+// takeInherited returns the inherited listener matching the config, by name
+// or by address, or nil when there is none.
+func (s *Server) takeInherited(c listenerConfig) net.Listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, byName := range []bool{true, false} {
+		for i, il := range s.inherited {
+			if (byName && il.name == c.String()) || (!byName && matchesAddr(c, il.listener.Addr())) {
+				s.inherited = append(s.inherited[:i], s.inherited[i+1:]...)
+				s.logger.Info("msg", "listener inherited", "addr", c.String())
+				return il.listener
+			}
+		}
+	}
+	return nil
+}
+
+// matchesAddr reports whether the address of a listener is the address of
+// the config.
+func matchesAddr(c listenerConfig, addr net.Addr) bool {
+	switch a := addr.(type) {
+	case *net.UnixAddr:
+		return c.network == "unix" && a.Name == c.addr
+	case *net.TCPAddr:
+		if c.network != "tcp" {
+			return false
+		}
+		host, port, err := net.SplitHostPort(c.addr)
+		if err != nil || port != strconv.Itoa(a.Port) {
+			return false
+		}
+		if host == "" {
+			return a.IP.IsUnspecified()
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && (ip.Equal(a.IP) || (ip.IsUnspecified() && a.IP.IsUnspecified()))
+	}
+	return false
+}
+
+// closeInherited closes the inherited listeners which are not used by the
+// server.
+func (s *Server) closeInherited() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, il := range s.inherited {
+		s.logger.Warn("msg", "closing unused inherited listener", "name", il.name, "addr", il.listener.Addr().String())
+		il.listener.Close()
+	}
+	s.inherited = nil
+}
+
+// notifyReady signals that the server is ready to the parent process of a
+// restart, and to systemd.
+func (s *Server) notifyReady() {
+	if fd := os.Getenv(listenReadyFDEnv); fd != "" {
+		os.Unsetenv(listenReadyFDEnv)
+		if n, err := strconv.Atoi(fd); err == nil {
+			f := os.NewFile(uintptr(n), "ready")
+			if _, err := f.Write([]byte{1}); err != nil {
+				s.logger.Error("msg", "failed to signal readiness", "error", err)
+			}
+			f.Close()
+		}
+	}
+
+	if socket := os.Getenv(notifySocketEnv); socket != "" {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+		if err != nil {
+			s.logger.Error("msg", "failed to notify systemd", "error", err)
+			return
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte("READY=1")); err != nil {
+			s.logger.Error("msg", "failed to notify systemd", "error", err)
+		}
+	}
+}
+
+// Restart starts a new process of the executable, with the same arguments,
+// passing it the listeners of the server. It returns the new process once it
+// signals that it is ready, i.e. once it serves the listeners, and the caller
+// should then drain the server with Shutdown. The new process must enable
+// WithServerInheritance. When the new process fails to become ready within
+// the context's deadline, or 30s, it is killed and an error is returned.
+func (s *Server) Restart(ctx context.Context) (*os.Process, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultRestartTimeout)
+	defer cancel()
+
+	path, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restart: %w", err)
+	}
+	args := s.restartArgs
+	if args == nil {
+		args = os.Args
+	}
+
+	s.mu.RLock()
+	open := make([]openListener, len(s.open))
+	copy(open, s.open)
+	s.mu.RUnlock()
+
+	var (
+		files []*os.File
+		names []string
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, ol := range open {
+		fl, ok := ol.listener.(filer)
+		if !ok {
+			return nil, fmt.Errorf("failed to restart: listener %s cannot be passed", ol.name)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return nil, fmt.Errorf("failed to restart: %w", err)
+		}
+		files = append(files, f)
+		names = append(names, ol.name)
+	}
+
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restart: %w", err)
+	}
+	defer ready.Close()
+
+	env := make([]string, 0, len(os.Environ())+3)
+	for _, e := range os.Environ() {
+		switch strings.SplitN(e, "=", 2)[0] {
+		case listenPIDEnv, listenFDsEnv, listenFDNamesEnv, listenReadyFDEnv:
+			continue
+		}
+		env = append(env, e)
+	}
+	env = append(env,
+		listenFDsEnv+"="+strconv.Itoa(len(files)),
+		listenFDNamesEnv+"="+strings.Join(names, ":"),
+		listenReadyFDEnv+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+
+	cmd := &exec.Cmd{
+		Path:       path,
+		Args:       args,
+		Env:        env,
+		Stdin:      os.Stdin,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		ExtraFiles: append(files, readyW),
+	}
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restart: %w", err)
+	}
+	s.logger.Info("msg", "restarting", "pid", cmd.Process.Pid)
+
+	// Wait for the readiness signal. The pipe is closed without a signal when
+	// the new process exits.
+	result := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(ready, b); err != nil {
+			result <- errors.New("new process exited before becoming ready")
+			return
+		}
+		result <- nil
+	}()
+
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("new process not ready: %w", ctx.Err())
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("failed to restart: %w", err)
+	}
+
+	// The new process serves the Unix sockets from now on: they must not be
+	// removed when this server's listeners are closed.
+	for _, ol := range open {
+		if ul, ok := ol.listener.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+
+	s.logger.Info("msg", "restarted", "pid", cmd.Process.Pid)
+	return cmd.Process, nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// helperEnv runs TestInheritHelper as the new process of a restart, or as
+	// a socket activated process, serving on the address it holds.
+	helperEnv = "HTTP_INHERIT_HELPER_ADDR"
+)
+
+type (
+	textService struct {
+		text string
+	}
+)
+
+func (t textService) Register(r *mux.Router) {
+	r.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(t.text))
+	}).Methods(http.MethodGet)
+}
+
+func (t textService) Name() string {
+	return "textService"
+}
+
+// TestInheritHelper is the process started by the inheritance tests. It exits
+// without reporting, so its output does not mix with the output of the tests.
+func TestInheritHelper(t *testing.T) {
+	addr := os.Getenv(helperEnv)
+	if addr == "" {
+		t.Skip("helper process")
+	}
+
+	s := NewServer(WithServerAddr(addr), WithServerInheritance())
+	s.Register(textService{text: "child"})
+	if err := s.Run(context.Background()); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func helperArgs() []string {
+	return []string{os.Args[0], "-test.run=^TestInheritHelper$"}
+}
+
+func getText(t *testing.T, url string) string {
+	t.Helper()
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i := 0; i < 100; i++ {
+		if resp, err = http.Get(url); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
+
+func stop(t *testing.T, p *os.Process) {
+	t.Helper()
+
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	state, err := p.Wait()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !state.Success() {
+		t.Errorf("Unexpected exit: %s", state)
+	}
+}
+
+func TestServerRestart(t *testing.T) {
+	const addr = "127.0.0.1:4250"
+	t.Setenv(helperEnv, addr)
+
+	s := NewServer(WithServerAddr(addr), WithServerInheritance())
+	s.Register(textService{text: "parent"})
+	s.restartArgs = helperArgs()
+
+	result := make(chan error, 1)
+	go func() { result <- s.Serve() }()
+
+	if text := getText(t, "http://"+addr+"/text"); text != "parent" {
+		t.Fatalf("Unexpected text: %s", text)
+	}
+
+	p, err := s.Restart(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer stop(t, p)
+
+	// The old process drains, while the new process serves the listener.
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if text := getText(t, "http://"+addr+"/text"); text != "child" {
+		t.Errorf("Unexpected text: %s", text)
+	}
+}
+
+func TestServerRestartFailure(t *testing.T) {
+	// The new process fails to listen: no listener is passed to it.
+	t.Setenv(helperEnv, "127.0.0.1:4251")
+	l, err := net.Listen("tcp", "127.0.0.1:4251")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer l.Close()
+
+	s := NewServer(WithServerInheritance())
+	s.restartArgs = helperArgs()
+
+	if _, err := s.Restart(context.Background()); err == nil || err.Error() != "failed to restart: new process exited before becoming ready" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSocketActivation(t *testing.T) {
+	const addr = "127.0.0.1:4252"
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	l.Close()
+
+	// A stand-in for the systemd notification socket.
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	notify, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer notify.Close()
+
+	// The listener is matched by address, without a name.
+	cmd := exec.Command(os.Args[0], helperArgs()[1:]...)
+	cmd.Env = append(os.Environ(), helperEnv+"="+addr, "LISTEN_FDS=1", "NOTIFY_SOCKET="+socket)
+	cmd.ExtraFiles = []*os.File{f}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.Close()
+	defer stop(t, cmd.Process)
+
+	notify.SetReadDeadline(time.Now().Add(10 * time.Second))
+	b := make([]byte, 64)
+	n, err := notify.Read(b)
+	if err != nil || string(b[:n]) != "READY=1" {
+		t.Fatalf("Unexpected notification: %q %v", b[:n], err)
+	}
+
+	if text := getText(t, "http://"+addr+"/text"); text != "child" {
+		t.Errorf("Unexpected text: %s", text)
+	}
+}
+
+func TestServeInheritedCleanup(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer busy.Close()
+
+	unused, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer unused.Close()
+
+	// The admin listen fails: the unused inherited listener must be closed.
+	s := NewServer(WithServerAddr("127.0.0.1:0"), WithServerAdminAddr(busy.Addr().String()))
+	s.inherited = []inheritedListener{{name: "unused", listener: unused}}
+	if err := s.Serve(); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	unused.(*net.TCPListener).SetDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := unused.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected the inherited listener to be closed: %v", err)
+	}
+}
+
+func TestMatchesAddr(t *testing.T) {
+	tests := []struct {
+		config   listenerConfig
+		addr     net.Addr
+		expected bool
+	}{
+		{config: listenerConfig{network: "tcp", addr: ":8443"}, addr: &net.TCPAddr{IP: net.IPv6unspecified, Port: 8443}, expected: true},
+		{config: listenerConfig{network: "tcp", addr: "0.0.0.0:8443"}, addr: &net.TCPAddr{IP: net.IPv6unspecified, Port: 8443}, expected: true},
+		{config: listenerConfig{network: "tcp", addr: "127.0.0.1:8443"}, addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8443}, expected: true},
+		{config: listenerConfig{network: "tcp", addr: "127.0.0.1:8443"}, addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9090}},
+		{config: listenerConfig{network: "tcp", addr: "127.0.0.1:8443"}, addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 8443}},
+		{config: listenerConfig{network: "unix", addr: "/run/s.sock"}, addr: &net.UnixAddr{Name: "/run/s.sock", Net: "unix"}, expected: true},
+		{config: listenerConfig{network: "tcp", addr: "/run/s.sock"}, addr: &net.UnixAddr{Name: "/run/s.sock", Net: "unix"}},
+	}
+
+	for _, test := range tests {
+		if actual := matchesAddr(test.config, test.addr); actual != test.expected {
+			t.Errorf("%s %s: Expected %t, Actual: %t", test.config, test.addr, test.expected, actual)
+		}
+	}
+}
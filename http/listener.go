@@ -0,0 +1,149 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+type (
+	// listenerConfig describes a listener serving the router of the server,
+	// in addition to the listener on the server's address.
+	listenerConfig struct {
+		network string
+		addr    string
+		tls     *tls.Config
+		mode    os.FileMode
+	}
+)
+
+// String returns the address of the listener, prefixed by unix: for a Unix
+// domain socket.
+func (c listenerConfig) String() string {
+	if c.network == "unix" {
+		return "unix:" + c.addr
+	}
+	return c.addr
+}
+
+// listen opens the listener described by the config, or takes the matching
+// listener inherited from the parent process, see WithServerInheritance.
+func (s *Server) listen(c listenerConfig) (net.Listener, error) {
+	l := s.takeInherited(c)
+	if l == nil {
+		if c.network == "unix" {
+			if err := removeStaleSocket(c.addr); err != nil {
+				return nil, fmt.Errorf("failed to listen on %s: %w", c, err)
+			}
+		}
+
+		var err error
+		if l, err = net.Listen(c.network, c.addr); err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", c, err)
+		}
+
+		if c.network == "unix" && c.mode != 0 {
+			if err := os.Chmod(c.addr, c.mode); err != nil {
+				l.Close()
+				return nil, fmt.Errorf("failed to set the mode of %s: %w", c.addr, err)
+			}
+		}
+	}
+
+	// Track the listener, so it can be passed to a new process by Restart.
+	s.mu.Lock()
+	s.open = append(s.open, openListener{name: c.String(), listener: l})
+	s.mu.Unlock()
+
+	if c.tls != nil {
+		cfg := c.tls.Clone()
+		if len(cfg.NextProtos) == 0 {
+			cfg.NextProtos = []string{"h2", "http/1.1"}
+		}
+		l = tls.NewListener(l, cfg)
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes the socket file left behind by a previous process,
+// which would otherwise prevent the listen. The socket is only removed when
+// connections to it are refused, i.e. when no process serves it. Other files
+// are left in place.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	case fi.Mode()&os.ModeSocket == 0:
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	switch {
+	case err == nil:
+		conn.Close()
+		return fmt.Errorf("%s is in use", path)
+	case !errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("failed to check whether %s is in use: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+// serveRedirect serves the redirect listener, until the server is shutdown.
+func (s *Server) serveRedirect() {
+	s.logger.Info("msg", "begin serving redirect", "addr", s.redirectAddr, "target", s.redirectTarget)
+	defer s.logger.Info("msg", "serving redirect complete", "addr", s.redirectAddr)
+
+	if err := s.redirectServer.Serve(s.redirectListener); err != nil && err != http.ErrServerClosed {
+		s.logger.Error("msg", "failed to serve redirect", "addr", s.redirectAddr, "error", err)
+	}
+}
+
+// redirectHandler returns a handler which permanently redirects the requests
+// to https on the target address. The host of the request is kept when the
+// target has no host, and the port is omitted when it is 443.
+func redirectHandler(target string) http.Handler {
+	targetHost, targetPort, err := net.SplitHostPort(target)
+	if err != nil {
+		targetHost, targetPort = target, ""
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := targetHost
+		if host == "" {
+			host = r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+		}
+		if targetPort != "" && targetPort != "443" {
+			host = net.JoinHostPort(host, targetPort)
+		}
+
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
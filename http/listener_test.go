@@ -0,0 +1,217 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerListeners(t *testing.T) {
+	t.Run("serve", func(t *testing.T) {
+		tlsConfig := setupTLS(t, "../test/insecure/cert.pem", "../test/insecure/key.pem", "../test/insecure/rootCA.pem", false)
+		socket := filepath.Join(t.TempDir(), "server.sock")
+
+		s := NewServer(
+			WithServerAddr("127.0.0.1:4247"),
+			WithServerTLSListener("127.0.0.1:4248", tlsConfig),
+			WithServerUnixListener(socket, 0600),
+			WithServerRedirect("127.0.0.1:4249", ":4248"),
+		)
+		s.Register(&mockService{})
+
+		result := make(chan error, 1)
+		go func() { result <- s.Serve() }()
+
+		clients := []struct {
+			name   string
+			url    string
+			client *http.Client
+		}{
+			{name: "plain", url: "http://127.0.0.1:4247/foo", client: &http.Client{}},
+			{
+				name: "tls", url: "https://127.0.0.1:4248/foo",
+				client: &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}},
+			},
+			{
+				name: "unix", url: "http://unix/foo",
+				client: &http.Client{Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+					},
+				}},
+			},
+			{
+				name: "redirect", url: "http://127.0.0.1:4249/foo?bar=1",
+				client: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				}},
+			},
+		}
+
+		for _, c := range clients {
+			var (
+				resp *http.Response
+				err  error
+			)
+			for i := 0; i < 100; i++ {
+				if resp, err = c.client.Get(c.url); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("%s: Unexpected error: %s", c.name, err)
+			}
+			resp.Body.Close()
+
+			if c.name == "redirect" {
+				if resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") != "https://127.0.0.1:4248/foo?bar=1" {
+					t.Errorf("%s: Unexpected response: %d %s", c.name, resp.StatusCode, resp.Header.Get("Location"))
+				}
+				continue
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("%s: Unexpected status: %d", c.name, resp.StatusCode)
+			}
+		}
+
+		fi, err := os.Stat(socket)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("Unexpected socket mode: %s", fi.Mode())
+		}
+
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Unexpected err: %s", err)
+		}
+		if err := <-result; err != nil {
+			t.Errorf("Unexpected err: %s", err)
+		}
+		if _, err := os.Stat(socket); !os.IsNotExist(err) {
+			t.Errorf("Expected the socket to be removed: %v", err)
+		}
+	})
+
+	t.Run("serve failure", func(t *testing.T) {
+		// Without certificates, serving the main listener fails: the server
+		// must stop serving the other listeners, and return the error.
+		s := NewServer(
+			WithServerAddr("127.0.0.1:0"),
+			WithServerTLS(&tls.Config{}),
+			WithServerListener("127.0.0.1:0"),
+			WithServerAdminAddr("127.0.0.1:0"),
+		)
+
+		result := make(chan error, 1)
+		go func() { result <- s.Serve() }()
+
+		select {
+		case err := <-result:
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for Serve to return")
+		}
+	})
+
+	t.Run("stale socket", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "server.sock")
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		// Leave the socket file behind, as a crashed process would.
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+
+		s := &Server{}
+		l, err = s.listen(listenerConfig{network: "unix", addr: socket})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		l.Close()
+	})
+
+	t.Run("socket in use", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "server.sock")
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer l.Close()
+
+		s := &Server{}
+		_, err = s.listen(listenerConfig{network: "unix", addr: socket})
+		if err == nil || !strings.Contains(err.Error(), "is in use") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if _, err := os.Stat(socket); err != nil {
+			t.Errorf("Expected the socket to be kept: %s", err)
+		}
+	})
+
+	t.Run("not a socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "server.sock")
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		s := NewServer(WithServerAddr("127.0.0.1:0"), WithServerUnixListener(path, 0))
+		err := s.Serve()
+		if err == nil || !strings.Contains(err.Error(), "exists and is not a socket") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected the file to be kept: %s", err)
+		}
+	})
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		target   string
+		url      string
+		expected string
+	}{
+		{target: ":443", url: "http://example.com/foo", expected: "https://example.com/foo"},
+		{target: ":8443", url: "http://example.com:8080/foo?bar=1", expected: "https://example.com:8443/foo?bar=1"},
+		{target: "secure.example.com:443", url: "http://example.com/foo", expected: "https://secure.example.com/foo"},
+		{target: "secure.example.com", url: "http://example.com/", expected: "https://secure.example.com/"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		redirectHandler(test.target).ServeHTTP(w, httptest.NewRequest(http.MethodPost, test.url, nil))
+
+		if w.Code != http.StatusPermanentRedirect {
+			t.Errorf("Unexpected status: %d", w.Code)
+		}
+		if location := w.Header().Get("Location"); location != test.expected {
+			t.Errorf("\nExpected location: %s\nActual location:   %s", test.expected, location)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"arcadium.dev/core/internal/prom"
+	"arcadium.dev/core/log"
+)
+
+const (
+	// UnmatchedRoute is the path label of requests which matched no route.
+	UnmatchedRoute = "unmatched"
+)
+
+var (
+	// DefaultSizeBuckets are the default buckets of the request and response
+	// size histograms, from 64B to 16MB.
+	DefaultSizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+)
+
+type (
+	// MetricsOption provides options for configuring the metrics of the
+	// server, see WithServerMetrics.
+	MetricsOption interface {
+		apply(*metricsOptions)
+	}
+
+	metricsOptions struct {
+		registerer      prometheus.Registerer
+		namespace       string
+		subsystem       string
+		durationBuckets []float64
+		sizeBuckets     []float64
+		statusClass     bool
+	}
+
+	// metrics holds the collectors of the http metrics.
+	metrics struct {
+		statusClass bool
+
+		concurrent   prometheus.Gauge
+		inFlight     *prometheus.GaugeVec
+		count        *prometheus.CounterVec
+		seconds      *prometheus.CounterVec
+		duration     *prometheus.HistogramVec
+		requestSize  *prometheus.HistogramVec
+		responseSize *prometheus.HistogramVec
+		errors       *prometheus.CounterVec
+	}
+
+	// route holds the labels of the route matched by a request. It is filled
+	// by the routeLabels middleware, once the router has matched the route.
+	route struct {
+		path    string
+		service string
+	}
+
+	// countingBody counts the bytes read from a request body.
+	countingBody struct {
+		io.ReadCloser
+		n int64
+	}
+
+	contextKey int
+)
+
+const (
+	routeContextKey contextKey = iota + 1
+	metricsContextKey
+)
+
+var (
+	defaultMetricsOnce sync.Once
+	defaultMetrics     *metrics
+)
+
+// newMetrics creates the collectors and registers them with the registerer
+// given by the options, by default the prometheus default registerer.
+func newMetrics(o metricsOptions) (*metrics, error) {
+	reg := o.registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if o.durationBuckets == nil {
+		o.durationBuckets = prometheus.DefBuckets
+	}
+	if o.sizeBuckets == nil {
+		o.sizeBuckets = DefaultSizeBuckets
+	}
+	labels := []string{"service", "method", "path"}
+	statusLabels := []string{"service", "method", "path", "status"}
+	ns, sub := o.namespace, o.subsystem
+
+	m := &metrics{
+		statusClass: o.statusClass,
+		concurrent: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_concurrent_requests",
+			Help:      "The number of concurrent http requests being processed",
+		}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_requests_in_flight",
+			Help:      "The number of http requests being processed by route",
+		}, labels),
+		count: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_request_count",
+			Help:      "Total number of requests by route and status",
+		}, statusLabels),
+		seconds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_request_seconds",
+			Help:      "Total amount of request time by route and status, in seconds",
+		}, statusLabels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_request_seconds_histogram",
+			Help:      "The request time by route and status, in seconds.",
+			Buckets:   o.durationBuckets,
+		}, statusLabels),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_request_size_bytes",
+			Help:      "The request body size by route, in bytes.",
+			Buckets:   o.sizeBuckets,
+		}, labels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_response_size_bytes",
+			Help:      "The response body size by route and status, in bytes.",
+			Buckets:   o.sizeBuckets,
+		}, statusLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "http_error_count",
+			Help:      "Total number of http errors by error status.",
+		}, []string{"status"}),
+	}
+
+	var err error
+	register := func(c prometheus.Collector) prometheus.Collector {
+		c, rerr := prom.Register(reg, c)
+		if err == nil && rerr != nil {
+			err = fmt.Errorf("failed to register http metrics: %w", rerr)
+		}
+		return c
+	}
+	m.concurrent = register(m.concurrent).(prometheus.Gauge)
+	m.inFlight = register(m.inFlight).(*prometheus.GaugeVec)
+	m.count = register(m.count).(*prometheus.CounterVec)
+	m.seconds = register(m.seconds).(*prometheus.CounterVec)
+	m.duration = register(m.duration).(*prometheus.HistogramVec)
+	m.requestSize = register(m.requestSize).(*prometheus.HistogramVec)
+	m.responseSize = register(m.responseSize).(*prometheus.HistogramVec)
+	m.errors = register(m.errors).(*prometheus.CounterVec)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// instrument returns a handler which measures each request handled by next,
+// labelled with the route filled in by the routeLabels middleware.
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt := &route{path: UnmatchedRoute}
+		ctx := context.WithValue(r.Context(), routeContextKey, rt)
+		r = r.WithContext(context.WithValue(ctx, metricsContextKey, m))
+		m.serve(next, w, r, func() route { return *rt })
+	})
+}
+
+// serve measures the request handled by next. As the route is only known
+// once the router has matched it, the labels are obtained after the request
+// is handled, and the in flight gauge is updated then for the whole request.
+func (m *metrics) serve(next http.Handler, w http.ResponseWriter, r *http.Request, labels func() route) {
+	m.concurrent.Inc()
+	defer m.concurrent.Dec()
+
+	var body *countingBody
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingBody{ReadCloser: r.Body}
+		r.Body = body
+	}
+	rw := wrapResponseWriter(w)
+	start := time.Now()
+
+	inFlight := func(rt route) prometheus.Gauge {
+		return m.inFlight.WithLabelValues(rt.service, r.Method, rt.path)
+	}
+	rt := labels()
+	if rt.path != UnmatchedRoute {
+		// The route is known up front for the standalone middleware.
+		inFlight(rt).Inc()
+		defer inFlight(rt).Dec()
+	}
+
+	next.ServeHTTP(rw, r)
+
+	elapsed := time.Since(start).Seconds()
+	rt = labels()
+	status := m.status(rw.Status())
+
+	m.count.WithLabelValues(rt.service, r.Method, rt.path, status).Inc()
+	m.seconds.WithLabelValues(rt.service, r.Method, rt.path, status).Add(elapsed)
+	m.duration.WithLabelValues(rt.service, r.Method, rt.path, status).Observe(elapsed)
+	m.responseSize.WithLabelValues(rt.service, r.Method, rt.path, status).Observe(float64(rw.Size()))
+
+	size := r.ContentLength
+	if body != nil && body.n > size {
+		size = body.n
+	}
+	if size < 0 {
+		size = 0
+	}
+	m.requestSize.WithLabelValues(rt.service, r.Method, rt.path).Observe(float64(size))
+}
+
+// getDefaultMetrics returns the metrics used outside of a server created with
+// the WithServerMetrics option, registered with the prometheus default
+// registerer on first use.
+func getDefaultMetrics() *metrics {
+	defaultMetricsOnce.Do(func() {
+		var err error
+		if defaultMetrics, err = newMetrics(metricsOptions{}); err != nil {
+			log.Error("msg", "failed to create http metrics", "error", err)
+		}
+	})
+	return defaultMetrics
+}
+
+// metricsFromContext returns the metrics of the server handling the request,
+// or else the default metrics.
+func metricsFromContext(ctx context.Context) *metrics {
+	if m, ok := ctx.Value(metricsContextKey).(*metrics); ok {
+		return m
+	}
+	return getDefaultMetrics()
+}
+
+// status returns the status label, the status code or its class, e.g. 2xx.
+func (m *metrics) status(code int) string {
+	if code == 0 {
+		code = http.StatusOK
+	}
+	if m.statusClass {
+		return strconv.Itoa(code/100) + "xx"
+	}
+	return strconv.Itoa(code)
+}
+
+// routeLabels is middleware which fills in the route of the request for the
+// instrumenting handler, and tracks the requests in flight by route.
+func (s *Server) routeLabels(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := r.Context().Value(routeContextKey).(*route)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		*rt = s.route(r)
+
+		g := s.metrics.inFlight.WithLabelValues(rt.service, r.Method, rt.path)
+		g.Inc()
+		defer g.Dec()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// route returns the path template and service of the route matched by the
+// request.
+func (s *Server) route(r *http.Request) route {
+	rt := route{path: UnmatchedRoute}
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return rt
+	}
+	if tmpl, err := current.GetPathTemplate(); err == nil {
+		rt.path = tmpl
+	}
+	s.mu.RLock()
+	rt.service = s.routes[current]
+	s.mu.RUnlock()
+	return rt
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// WithMetricsRegisterer sets the registerer of the server's collectors. The
+// default is the prometheus default registerer.
+func WithMetricsRegisterer(reg prometheus.Registerer) MetricsOption {
+	return newMetricsOption(func(o *metricsOptions) {
+		o.registerer = reg
+	})
+}
+
+// WithMetricsNamespace sets the namespace and subsystem prefixed to the names
+// of the server's metrics.
+func WithMetricsNamespace(namespace, subsystem string) MetricsOption {
+	return newMetricsOption(func(o *metricsOptions) {
+		o.namespace = namespace
+		o.subsystem = subsystem
+	})
+}
+
+// WithMetricsBuckets sets the buckets of the request duration histogram, in
+// seconds. The default is prometheus.DefBuckets.
+func WithMetricsBuckets(buckets ...float64) MetricsOption {
+	return newMetricsOption(func(o *metricsOptions) {
+		o.durationBuckets = buckets
+	})
+}
+
+// WithMetricsSizeBuckets sets the buckets of the request and response size
+// histograms, in bytes. The default is DefaultSizeBuckets.
+func WithMetricsSizeBuckets(buckets ...float64) MetricsOption {
+	return newMetricsOption(func(o *metricsOptions) {
+		o.sizeBuckets = buckets
+	})
+}
+
+// WithMetricsStatusClass labels the metrics with the status class, e.g. 2xx,
+// rather than the status code, limiting the number of series.
+func WithMetricsStatusClass() MetricsOption {
+	return newMetricsOption(func(o *metricsOptions) {
+		o.statusClass = true
+	})
+}
+
+type (
+	metricsOption struct {
+		f func(*metricsOptions)
+	}
+)
+
+func newMetricsOption(f func(*metricsOptions)) metricsOption {
+	return metricsOption{f: f}
+}
+
+func (o metricsOption) apply(opts *metricsOptions) {
+	o.f(opts)
+}
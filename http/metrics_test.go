@@ -0,0 +1,130 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	cerrors "arcadium.dev/core/errors"
+)
+
+func TestServerMetrics(t *testing.T) {
+	s := NewServer(WithServerMetrics(
+		WithMetricsRegisterer(prometheus.NewRegistry()),
+		WithMetricsStatusClass(),
+		WithMetricsBuckets(0.1, 1),
+		WithMetricsSizeBuckets(10, 100),
+	))
+	s.Register(&mockService{})
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/foo", nil),
+		httptest.NewRequest(http.MethodGet, "/foo", nil),
+		httptest.NewRequest(http.MethodPost, "/unknown", strings.NewReader("0123456789abcdef")),
+	}
+	for _, r := range requests {
+		s.server.Handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	m := s.metrics
+	if c := testutil.ToFloat64(m.count.WithLabelValues("mockService", "GET", "/foo", "2xx")); c != 2 {
+		t.Errorf("Unexpected count: %f", c)
+	}
+	if c := testutil.ToFloat64(m.count.WithLabelValues("", "POST", UnmatchedRoute, "4xx")); c != 1 {
+		t.Errorf("Unexpected count: %f", c)
+	}
+	if c := testutil.ToFloat64(m.inFlight.WithLabelValues("mockService", "GET", "/foo")); c != 0 {
+		t.Errorf("Unexpected in flight: %f", c)
+	}
+
+	expected := `
+# HELP http_request_size_bytes The request body size by route, in bytes.
+# TYPE http_request_size_bytes histogram
+http_request_size_bytes_bucket{method="GET",path="/foo",service="mockService",le="10"} 2
+http_request_size_bytes_bucket{method="GET",path="/foo",service="mockService",le="100"} 2
+http_request_size_bytes_bucket{method="GET",path="/foo",service="mockService",le="+Inf"} 2
+http_request_size_bytes_sum{method="GET",path="/foo",service="mockService"} 0
+http_request_size_bytes_count{method="GET",path="/foo",service="mockService"} 2
+http_request_size_bytes_bucket{method="POST",path="unmatched",service="",le="10"} 0
+http_request_size_bytes_bucket{method="POST",path="unmatched",service="",le="100"} 1
+http_request_size_bytes_bucket{method="POST",path="unmatched",service="",le="+Inf"} 1
+http_request_size_bytes_sum{method="POST",path="unmatched",service=""} 16
+http_request_size_bytes_count{method="POST",path="unmatched",service=""} 1
+`
+	if err := testutil.CollectAndCompare(m.requestSize, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Metrics)
+	router.HandleFunc("/bar/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hello"))
+	})
+
+	// The default metrics are global: compare the count with the count before
+	// the request.
+	count := getDefaultMetrics().count.WithLabelValues("", "GET", "/bar/{id}", "418")
+	before := testutil.ToFloat64(count)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bar/1", nil))
+
+	if c := testutil.ToFloat64(count) - before; c != 1 {
+		t.Errorf("Unexpected count: %f", c)
+	}
+	if c := testutil.ToFloat64(getDefaultMetrics().inFlight.WithLabelValues("", "GET", "/bar/{id}")); c != 0 {
+		t.Errorf("Unexpected in flight: %f", c)
+	}
+}
+
+func TestServerMetricsRegisterer(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		Response(r.Context(), w, cerrors.ErrNotFound)
+	}
+
+	// Two servers in one process, with separate metrics.
+	var regs []*prometheus.Registry
+	for i := 0; i < 2; i++ {
+		reg := prometheus.NewRegistry()
+		regs = append(regs, reg)
+
+		s := NewServer(WithServerMetrics(WithMetricsRegisterer(reg), WithMetricsNamespace("arcadium", "api")))
+		s.router.HandleFunc("/missing", handler)
+		for j := 0; j <= i; j++ {
+			s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+		}
+	}
+
+	for i, reg := range regs {
+		expected := fmt.Sprintf(`
+# HELP arcadium_api_http_error_count Total number of http errors by error status.
+# TYPE arcadium_api_http_error_count counter
+arcadium_api_http_error_count{status="404"} %d
+`, i+1)
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "arcadium_api_http_error_count"); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -16,71 +16,41 @@ package http
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"arcadium.dev/core/log"
 )
 
-var (
-	concurrentRequests prometheus.Gauge
-	requestCount       *prometheus.CounterVec
-	requestSeconds     *prometheus.CounterVec
-	requestTime        *prometheus.HistogramVec
-)
-
-func init() {
-	concurrentRequests = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "http_concurrent_requests",
-		Help: "The number of concurrent http requests being processed",
-	})
-
-	requestCount = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "http_request_count",
-		Help: "Total number of requests by route",
-	}, []string{"method", "path"})
-
-	requestSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "http_request_seconds",
-		Help: "Total amount of request time by route, in seconds",
-	}, []string{"method", "path"})
-
-	requestTime = promauto.NewHistogramVec(prometheus.HistogramOpts{
-		Name: "http_request_seconds_histogram",
-		Help: "The request time by route, in seconds.",
-	}, []string{"method", "path"})
-}
-
 // Metrics is middleware which provides tracking of key metrics for incoming
-// HTTP requests.
+// HTTP requests, registered with the prometheus default registerer. The
+// service label is left empty. The WithServerMetrics option is preferred: it
+// provides the service label, measures requests which match no route, and
+// allows the registerer to be given. Requests to such a server are already
+// measured, and are passed through by this middleware.
 func Metrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Track concurrent requests.
-		concurrentRequests.Inc()
-		defer concurrentRequests.Dec()
+		if _, ok := r.Context().Value(metricsContextKey).(*metrics); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		m := getDefaultMetrics()
+		if m == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
 
 		// Obtain the template for the requested route.
-		tmpl := ""
-		if route := mux.CurrentRoute(r); route != nil {
-			var err error
-			if tmpl, err = route.GetPathTemplate(); err != nil {
-				log.Error("msg", "failed to get path template", "route", route, "error", err.Error())
+		rt := route{path: UnmatchedRoute}
+		if current := mux.CurrentRoute(r); current != nil {
+			tmpl, err := current.GetPathTemplate()
+			if err != nil {
+				log.Error("msg", "failed to get path template", "route", current, "error", err)
+			} else {
+				rt.path = tmpl
 			}
 		}
 
-		// Time the handler.
-		t := time.Now()
-
-		// Delegate to the next handler in the middleware chain.
-		next.ServeHTTP(w, r)
-
-		// Track the request.
-		s := float64(time.Since(t).Seconds())
-		requestCount.WithLabelValues(r.Method, tmpl).Inc()
-		requestSeconds.WithLabelValues(r.Method, tmpl).Add(s)
-		requestTime.WithLabelValues(r.Method, tmpl).Observe(s)
+		m.serve(next, w, r, func() route { return rt })
 	})
 }
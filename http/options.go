@@ -16,10 +16,13 @@ package http // import "arcadium.dev/core/server/http"
 
 import (
 	"crypto/tls"
+	"os"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"arcadium.dev/core/build"
+	"arcadium.dev/core/health"
 	"arcadium.dev/core/log"
 )
 
@@ -44,6 +47,105 @@ func WithServerTLS(cfg *tls.Config) ServerOption {
 	})
 }
 
+// WithServerListener serves the router of the server on an additional plain
+// TCP listener on the given address.
+func WithServerListener(addr string) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.listeners = append(s.listeners, listenerConfig{network: "tcp", addr: addr})
+	})
+}
+
+// WithServerTLSListener serves the router of the server on an additional TLS
+// listener on the given address.
+func WithServerTLSListener(addr string, cfg *tls.Config) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.listeners = append(s.listeners, listenerConfig{network: "tcp", addr: addr, tls: cfg})
+	})
+}
+
+// WithServerUnixListener serves the router of the server on a Unix domain
+// socket at the given path, with the given permissions, e.g. 0660, unless the
+// mode is zero. A socket left behind at the path by a previous process is
+// removed.
+func WithServerUnixListener(path string, mode os.FileMode) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.listeners = append(s.listeners, listenerConfig{network: "unix", addr: path, mode: mode})
+	})
+}
+
+// WithServerRedirect enables a listener on the given address which permanently
+// redirects plain HTTP clients to https on the target address, e.g. ":443" or
+// "example.com:8443". The host of the request is kept when the target has no
+// host.
+func WithServerRedirect(addr, target string) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.redirectAddr = addr
+		s.redirectTarget = target
+	})
+}
+
+// WithServerInheritance enables the inheritance of listeners: the listeners
+// passed by systemd socket activation, see sd_listen_fds(3), or by the parent
+// process of a restart, are used in place of opening new listeners, matched by
+// name or by address. It also enables the restart of the server by SIGHUP, see
+// Run and Restart, and the readiness notifications of systemd.
+func WithServerInheritance() ServerOption {
+	return newServerOption(func(s *Server) {
+		s.inherit = true
+	})
+}
+
+// WithServerAdminAddr enables the admin listener on the given address, e.g.
+// ":9090". The admin listener serves /metrics, separately from the services,
+// and is started by Serve and stopped by Shutdown.
+func WithServerAdminAddr(addr string) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.adminAddr = addr
+	})
+}
+
+// WithServerHealth serves the /livez, /readyz and /healthz handlers of the
+// given health checks, on the admin listener when it is enabled, otherwise
+// alongside the services. Readiness fails once Shutdown begins.
+func WithServerHealth(h *health.Health) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.health = h
+	})
+}
+
+// WithServerBuildInfo serves the build information as JSON on /buildinfo, on
+// the admin listener when it is enabled, otherwise alongside the services, see
+// BuildInfoHandler. It also registers the build_info gauge, with the
+// registerer and namespace given to WithServerMetrics, if any.
+func WithServerBuildInfo(info build.Information, opts ...BuildInfoOption) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.buildInfo = &info
+		s.buildInfoOpts = opts
+	})
+}
+
+// WithServerDebug serves the debug routes, i.e. pprof, a goroutine dump, the
+// garbage collector statistics and expvar, under /debug on the admin listener.
+// The routes are never served alongside the services: they are not served
+// when the admin listener is not enabled.
+func WithServerDebug(opts ...DebugOption) ServerOption {
+	return newServerOption(func(s *Server) {
+		o := debugOptions{}
+		for _, opt := range opts {
+			opt.apply(&o)
+		}
+		s.debugOpts = &o
+	})
+}
+
+// WithServerServiceTimeout sets the time given to each service implementing
+// Lifecycle to shutdown, within the shutdown timeout of the server.
+func WithServerServiceTimeout(timeout time.Duration) ServerOption {
+	return newServerOption(func(s *Server) {
+		s.serviceTimeout = timeout
+	})
+}
+
 // WithServerShutdownTimeout sets the timout for shutting down the server.
 func WithServerShutdownTimeout(timeout time.Duration) ServerOption {
 	return newServerOption(func(s *Server) {
@@ -65,6 +167,19 @@ func WithMiddleware(mw ...mux.MiddlewareFunc) ServerOption {
 	})
 }
 
+// WithServerMetrics measures the requests handled by the server, labelled
+// with the route, the status, and the name of the service which registered
+// the route. Requests which match no route are labelled with UnmatchedRoute.
+func WithServerMetrics(opts ...MetricsOption) ServerOption {
+	return newServerOption(func(s *Server) {
+		o := &metricsOptions{}
+		for _, opt := range opts {
+			opt.apply(o)
+		}
+		s.metricsOpts = o
+	})
+}
+
 type (
 	serverOption struct {
 		f func(*Server)
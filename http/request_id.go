@@ -0,0 +1,170 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"arcadium.dev/core/log"
+)
+
+const (
+	// RequestIDHeader is the default header carrying the request ID.
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLen limits the length of an accepted request ID.
+	maxRequestIDLen = 128
+
+	// crockford is the base32 alphabet of a ULID.
+	crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+type (
+	// RequestIDOption provides options for configuring the RequestID
+	// middleware.
+	RequestIDOption interface {
+		apply(*requestIDOptions)
+	}
+
+	requestIDOptions struct {
+		header   string
+		generate func() string
+	}
+)
+
+// RequestID is middleware which accepts the request ID from the request
+// header, or generates one when the header is missing or invalid. The ID is
+// stored in the request's context, see log.RequestIDFromContext, added to the
+// context logger, and echoed in the response header. The ID is also included
+// in the error bodies written by Response.
+//
+// The middleware should be given to the server with the WithMiddleware option,
+// so it follows the creation of the request specific logger.
+func RequestID(opts ...RequestIDOption) mux.MiddlewareFunc {
+	o := requestIDOptions{
+		header:   RequestIDHeader,
+		generate: NewUUID,
+	}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(o.header)
+			if !validRequestID(id) {
+				id = o.generate()
+			}
+			w.Header().Set(o.header, id)
+
+			ctx := log.NewContextWithRequestID(r.Context(), id)
+			l := log.LoggerFromContext(ctx).With(log.RequestIDKey, id)
+			ctx = log.NewContextWithLogger(ctx, l)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WithRequestIDHeader sets the header carrying the request ID. The default is
+// X-Request-ID.
+func WithRequestIDHeader(header string) RequestIDOption {
+	return newRequestIDOption(func(o *requestIDOptions) {
+		o.header = header
+	})
+}
+
+// WithRequestIDGenerator sets the function generating request IDs, e.g. NewULID.
+// The default is NewUUID.
+func WithRequestIDGenerator(generate func() string) RequestIDOption {
+	return newRequestIDOption(func(o *requestIDOptions) {
+		o.generate = generate
+	})
+}
+
+// NewUUID returns a random (version 4) UUID.
+func NewUUID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40 // Version 4.
+	u[8] = (u[8] & 0x3f) | 0x80 // Variant RFC 4122.
+
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+// NewULID returns a ULID, a lexicographically sortable identifier made of a
+// millisecond timestamp and 80 random bits.
+func NewULID() string {
+	var u [16]byte
+	binary.BigEndian.PutUint64(u[:8], uint64(time.Now().UnixMilli())<<16)
+	rand.Read(u[6:])
+
+	// Encode the 128 bits as 26 base32 characters, 5 bits at a time, the
+	// first character holding the 3 most significant bits.
+	var b [26]byte
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	for i := 25; i >= 0; i-- {
+		b[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(b[:])
+}
+
+// validRequestID reports whether an ID given by a client may be used. IDs
+// are limited in length and to printable ASCII, so they are safe to log and
+// echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+type (
+	requestIDOption struct {
+		f func(*requestIDOptions)
+	}
+)
+
+func newRequestIDOption(f func(*requestIDOptions)) requestIDOption {
+	return requestIDOption{f: f}
+}
+
+func (o requestIDOption) apply(opts *requestIDOptions) {
+	o.f(opts)
+}
@@ -0,0 +1,100 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	cerrors "arcadium.dev/core/errors"
+	"arcadium.dev/core/log"
+)
+
+func TestRequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulid := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	handle := func(t *testing.T, mw func(http.Handler) http.Handler, r *http.Request) (*httptest.ResponseRecorder, string, *log.StringBuffer) {
+		t.Helper()
+
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		r = r.WithContext(log.NewContextWithLogger(r.Context(), l))
+
+		var id string
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = log.RequestIDFromContext(r.Context())
+			log.LoggerFromContext(r.Context()).Info("msg", "handled")
+			Response(r.Context(), w, cerrors.ErrNotFound)
+		}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w, id, b
+	}
+
+	t.Run("generated", func(t *testing.T) {
+		w, id, b := handle(t, RequestID(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if !uuid.MatchString(id) {
+			t.Errorf("Unexpected request id: %s", id)
+		}
+		checkHeader(t, w, RequestIDHeader, id)
+		if !strings.Contains(b.Index(0), "request_id="+id) {
+			t.Errorf("Unexpected log: %s", b.Index(0))
+		}
+		expected := `{"error":{"status":404,"detail":"not found","request_id":"` + id + `"}}` + "\n"
+		if w.Body.String() != expected {
+			t.Errorf("\nExpected body %s\nActual body   %s", expected, w.Body.String())
+		}
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Correlation-ID", "abc-123")
+		w, id, _ := handle(t, RequestID(WithRequestIDHeader("X-Correlation-ID")), r)
+
+		if id != "abc-123" {
+			t.Errorf("Unexpected request id: %s", id)
+		}
+		checkHeader(t, w, "X-Correlation-ID", "abc-123")
+	})
+
+	t.Run("invalid replaced", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(RequestIDHeader, "bad id\n")
+		_, id, _ := handle(t, RequestID(WithRequestIDGenerator(NewULID)), r)
+
+		if !ulid.MatchString(id) {
+			t.Errorf("Unexpected request id: %s", id)
+		}
+	})
+
+	t.Run("ulid sortable", func(t *testing.T) {
+		a := NewULID()
+		for i := 0; i < 100; i++ {
+			b := NewULID()
+			if !ulid.MatchString(b) || b[:10] < a[:10] {
+				t.Fatalf("Unexpected ulid: %s after %s", b, a)
+			}
+			a = b
+		}
+	})
+}
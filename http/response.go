@@ -22,9 +22,6 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-
 	cerrors "arcadium.dev/core/errors"
 	"arcadium.dev/core/log"
 )
@@ -39,29 +36,29 @@ func Response(ctx context.Context, w http.ResponseWriter, err error) {
 
 	switch {
 	case errors.Is(err, cerrors.ErrInvalidArgument):
-		logger.Warn("reason", err.Error())
+		logger.Warn("reason", err)
 		response(ctx, w, http.StatusBadRequest, err)
 
 	case errors.Is(err, cerrors.ErrNotFound):
-		logger.Warn("reason", err.Error())
+		logger.Warn("reason", err)
 		response(ctx, w, http.StatusNotFound, err)
 
 	case errors.Is(err, cerrors.ErrAlreadyExists):
-		logger.Warn("reason", err.Error())
+		logger.Warn("reason", err)
 		response(ctx, w, http.StatusConflict, err)
 
 	case errors.Is(err, cerrors.ErrNotImplemented):
-		logger.Error("error", err.Error())
+		logger.Error("error", err)
 		response(ctx, w, http.StatusNotImplemented, err)
 
 	default:
-		logger.Error("error", err.Error())
+		logger.Error("error", err)
 		response(ctx, w, http.StatusInternalServerError, err)
 	}
 }
 
 func response(ctx context.Context, w http.ResponseWriter, status int, e error) {
-	err := ResponseError{Status: status}
+	err := ResponseError{Status: status, RequestID: log.RequestIDFromContext(ctx)}
 	if e != nil {
 		err.Detail = e.Error()
 	}
@@ -70,7 +67,9 @@ func response(ctx context.Context, w http.ResponseWriter, status int, e error) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 
-	httpErrorCount.WithLabelValues(strconv.Itoa(err.Status)).Inc()
+	if m := metricsFromContext(ctx); m != nil {
+		m.errors.WithLabelValues(strconv.Itoa(err.Status)).Inc()
+	}
 
 	resp := struct {
 		Error ResponseError `json:"error,omitempty"`
@@ -79,7 +78,7 @@ func response(ctx context.Context, w http.ResponseWriter, status int, e error) {
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		log.LoggerFromContext(ctx).Error(
-			"msg", "unable to write error response", "error", err.Error(),
+			"msg", "unable to write error response", "error", err,
 		)
 	}
 }
@@ -93,6 +92,9 @@ type (
 		// Detail is a human-readable explanation specific to this occurrence of
 		// the problem.
 		Detail string `json:"detail,omitempty"`
+		// RequestID identifies the request which encountered the problem, see
+		// the RequestID middleware.
+		RequestID string `json:"request_id,omitempty"`
 	}
 )
 
@@ -100,10 +102,3 @@ type (
 func (e ResponseError) Error() string {
 	return fmt.Sprintf("status=%d, detail=%q", e.Status, e.Detail)
 }
-
-var (
-	httpErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "http_error_count",
-		Help: "Total number of http errors by error status.",
-	}, []string{"status"})
-)
@@ -20,13 +20,22 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"arcadium.dev/core/build"
+	cerrors "arcadium.dev/core/errors"
+	"arcadium.dev/core/health"
 	"arcadium.dev/core/log"
 )
 
@@ -55,21 +64,76 @@ type (
 		router     *mux.Router
 		middleware []mux.MiddlewareFunc
 
+		metricsOpts *metricsOptions
+		metrics     *metrics
+
+		adminAddr     string
+		adminListener net.Listener
+		adminServer   *http.Server
+		adminRouter   *mux.Router
+
+		listeners []listenerConfig
+
+		redirectAddr     string
+		redirectTarget   string
+		redirectListener net.Listener
+		redirectServer   *http.Server
+
+		inherit     bool
+		inherited   []inheritedListener
+		open        []openListener
+		restartArgs []string
+
+		health        *health.Health
+		buildInfo     *build.Information
+		buildInfoOpts []BuildInfoOption
+		debugOpts     *debugOptions
+
+		serviceTimeout time.Duration
+
+		// lifecycle serialises the start and the shutdown of the services,
+		// and shuttingDown prevents the start of services once the shutdown
+		// has begun.
+		lifecycle    sync.Mutex
+		shuttingDown int32
+
 		mu       sync.RWMutex
 		services []Service
+		started  map[int]bool
+		routes   map[*mux.Route]string
 	}
 
 	// Service defines the methods required by the Server to register with
 	// the service with the router.
+	//
+	// A service should also implement either Shutdown(), allowing the service
+	// to stop any long running background processes it may have, or the
+	// Lifecycle interface.
 	Service interface {
 		// Register will register this service with the given router.
 		Register(router *mux.Router)
 
 		// Name provides the name of the service.
 		Name() string
+	}
 
-		// Shutdown allows the service to stop any long running background processes it
-		// may have.
+	// Lifecycle is implemented by services which start background processes,
+	// and which report the failures of their startup and shutdown.
+	Lifecycle interface {
+		// Start starts the service's background processes. It is called by
+		// Serve, in registration order, before accepting requests. The
+		// context bounds the startup only.
+		Start(ctx context.Context) error
+
+		// Shutdown stops the service's background processes. It is called by
+		// the server's Shutdown, in reverse registration order, once the
+		// active requests have completed and any Start in progress has
+		// returned. It is also called when serving fails to start.
+		Shutdown(ctx context.Context) error
+	}
+
+	// shutdowner is implemented by the services without a Lifecycle.
+	shutdowner interface {
 		Shutdown()
 	}
 )
@@ -82,8 +146,14 @@ func NewServer(opts ...ServerOption) *Server {
 		server:          &http.Server{},
 		router:          mux.NewRouter(),
 		shutdownTimeout: defaultShutdownTimeout,
+		routes:          make(map[*mux.Route]string),
+		started:         make(map[int]bool),
+		adminServer:     &http.Server{},
+		adminRouter:     mux.NewRouter(),
+		redirectServer:  &http.Server{},
 	}
 	s.server.Handler = s.router
+	s.adminServer.Handler = s.adminRouter
 
 	// Load options.
 	for _, opt := range opts {
@@ -102,17 +172,93 @@ func NewServer(opts ...ServerOption) *Server {
 			msg = append(msg, "tls", "enabled")
 		}
 	}
+	if s.adminAddr != "" {
+		msg = append(msg, "admin_addr", s.adminAddr)
+	}
+	if len(s.listeners) > 0 {
+		addrs := make([]string, 0, len(s.listeners))
+		for _, l := range s.listeners {
+			addrs = append(addrs, l.String())
+		}
+		msg = append(msg, "listeners", strings.Join(addrs, ","))
+	}
+	if s.redirectAddr != "" {
+		msg = append(msg, "redirect_addr", s.redirectAddr)
+	}
 	s.logger.Info(msg...)
 
+	s.redirectServer.Handler = redirectHandler(s.redirectTarget)
+
+	if s.metricsOpts != nil {
+		var err error
+		if s.metrics, err = newMetrics(*s.metricsOpts); err != nil {
+			s.logger.Error("msg", "failed to create metrics", "error", err)
+		}
+	}
+
 	s.router.Use(s.recoverPanics)
 	s.router.Use(s.requestLogging)
+	if s.metrics != nil {
+		s.server.Handler = s.metrics.instrument(s.router)
+		s.router.Use(s.routeLabels)
+	}
 	if len(s.middleware) > 0 {
 		s.router.Use(s.middleware...)
 	}
 
+	s.adminRouter.Handle("/metrics", promhttp.HandlerFor(s.gatherer(), promhttp.HandlerOpts{})).Methods(http.MethodGet)
+
+	if s.health != nil {
+		router := s.infraRouter()
+		router.Handle("/livez", s.health.LivezHandler()).Methods(http.MethodGet)
+		router.Handle("/readyz", s.health.ReadyzHandler()).Methods(http.MethodGet)
+		router.Handle("/healthz", s.health.HealthzHandler()).Methods(http.MethodGet)
+	}
+
+	if s.buildInfo != nil {
+		o := metricsOptions{}
+		if s.metricsOpts != nil {
+			o = *s.metricsOpts
+		}
+		if err := registerBuildInfo(*s.buildInfo, o); err != nil {
+			s.logger.Error("msg", "failed to register build info", "error", err)
+		}
+		s.infraRouter().Handle("/buildinfo", BuildInfoHandler(*s.buildInfo, s.buildInfoOpts...)).Methods(http.MethodGet)
+	}
+
+	if s.debugOpts != nil {
+		if s.adminAddr != "" {
+			registerDebug(s.adminRouter, *s.debugOpts)
+		} else {
+			s.logger.Error("msg", "debug routes require the admin listener, not serving debug routes")
+		}
+	}
+
 	return s
 }
 
+// infraRouter returns the router of the infrastructure handlers, e.g. health
+// and build information: the admin router when the admin listener is enabled,
+// otherwise the router of the services.
+func (s *Server) infraRouter() *mux.Router {
+	if s.adminAddr != "" {
+		return s.adminRouter
+	}
+	return s.router
+}
+
+// gatherer returns the gatherer of the metrics served by the admin listener:
+// the registerer given to WithServerMetrics when it is also a gatherer, e.g. a
+// prometheus.Registry, otherwise the prometheus default gatherer.
+func (s *Server) gatherer() prometheus.Gatherer {
+	if s.metricsOpts != nil {
+		if g, ok := s.metricsOpts.registerer.(prometheus.Gatherer); ok {
+			return g
+		}
+	}
+	return prometheus.DefaultGatherer
+}
+
 // Register associates the given services with the router.
 func (s *Server) Register(services ...Service) {
 	s.mu.Lock()
@@ -122,16 +268,87 @@ func (s *Server) Register(services ...Service) {
 	r := s.router.PathPrefix("/").Subrouter()
 	for _, service := range services {
 		service.Register(r)
+		s.mapRoutes(r, service.Name())
 		s.logger.Info("msg", "service registered", "service", service.Name())
 	}
 }
 
+// mapRoutes associates the routes of the router not yet associated with a
+// service with the named service, for the service label of the metrics.
+func (s *Server) mapRoutes(r *mux.Router, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if _, ok := s.routes[route]; !ok {
+			s.routes[route] = name
+		}
+		return nil
+	})
+}
+
 // Serve accepts incoming connections, creating a new service goroutine for each. The
 // service goroutine reads requests and then call the handler to reply to them.
+// The services implementing Lifecycle are started first.
 func (s *Server) Serve() error {
+	return s.serve(context.Background())
+}
+
+func (s *Server) serve(ctx context.Context) error {
+	if s.inherit {
+		inherited, err := inheritListeners()
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.inherited = inherited
+		s.mu.Unlock()
+	}
+
+	if err := s.startServices(ctx); err != nil {
+		s.closeInherited()
+		if err == http.ErrServerClosed {
+			// The server was shutdown while the services were starting.
+			return nil
+		}
+		return err
+	}
+
 	var err error
-	if s.listener, err = net.Listen("tcp", s.addr); err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	if s.adminAddr != "" {
+		if s.adminListener, err = s.listen(listenerConfig{network: "tcp", addr: s.adminAddr}); err != nil {
+			s.closeInfra()
+			return cerrors.Join(err, s.stopServices())
+		}
+		go s.serveAdmin()
+	}
+	if s.redirectAddr != "" {
+		if s.redirectListener, err = s.listen(listenerConfig{network: "tcp", addr: s.redirectAddr}); err != nil {
+			s.closeInfra()
+			return cerrors.Join(err, s.stopServices())
+		}
+		go s.serveRedirect()
+	}
+	if s.listener, err = s.listen(listenerConfig{network: "tcp", addr: s.addr}); err != nil {
+		s.closeInfra()
+		return cerrors.Join(err, s.stopServices())
+	}
+	listeners := make([]net.Listener, 0, len(s.listeners))
+	for _, c := range s.listeners {
+		l, err := s.listen(c)
+		if err != nil {
+			s.closeInfra()
+			s.listener.Close()
+			for _, l := range listeners {
+				l.Close()
+			}
+			return cerrors.Join(err, s.stopServices())
+		}
+		listeners = append(listeners, l)
+	}
+	if s.inherit {
+		s.closeInherited()
+		s.notifyReady()
 	}
 
 	serviceNames := make([]string, 0)
@@ -145,37 +362,277 @@ func (s *Server) Serve() error {
 	s.logger.Info("msg", "begin serving", "services", services, "addr", s.addr)
 	defer s.logger.Info("msg", "serving complete", "services", services, "addr", s.addr)
 
+	// Serve the additional listeners.
+	results := make(chan error, len(listeners))
+	for i, l := range listeners {
+		go func(c listenerConfig, l net.Listener) {
+			s.logger.Info("msg", "begin serving listener", "addr", c.String())
+			if err := s.server.Serve(l); err != nil && err != http.ErrServerClosed {
+				results <- fmt.Errorf("failed to serve %s: %w", c, err)
+				return
+			}
+			results <- nil
+		}(s.listeners[i], l)
+	}
+
 	if s.server.TLSConfig != nil {
 		err = s.server.ServeTLS(s.listener, "", "")
 	} else {
 		err = s.server.Serve(s.listener)
 	}
 
-	if err == http.ErrServerClosed {
-		err = nil
+	errs := []error{}
+	switch {
+	case err == http.ErrServerClosed:
+	case err != nil:
+		// Serving failed: stop serving the other listeners, which would
+		// otherwise only stop at the shutdown.
+		s.server.Close()
+		s.closeInfra()
+		errs = append(errs, err, s.stopServices())
+	}
+	for range listeners {
+		errs = append(errs, <-results)
+	}
+	return cerrors.Join(errs...)
+}
+
+// closeInfra closes the admin and redirect servers, and the unused inherited
+// listeners, when serving fails to start.
+func (s *Server) closeInfra() {
+	s.closeInherited()
+	if s.adminListener != nil {
+		s.adminServer.Close()
+	}
+	if s.redirectListener != nil {
+		s.redirectServer.Close()
 	}
-	return err
 }
 
-// Shutdown stops the http server gracefully without interrupting any active connections.
-func (s *Server) Shutdown() {
-	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(s.shutdownTimeout))
+// serveAdmin serves the admin listener, until the server is shutdown.
+func (s *Server) serveAdmin() {
+	s.logger.Info("msg", "begin serving admin", "addr", s.adminAddr)
+	defer s.logger.Info("msg", "serving admin complete", "addr", s.adminAddr)
+
+	if err := s.adminServer.Serve(s.adminListener); err != nil && err != http.ErrServerClosed {
+		s.logger.Error("msg", "failed to serve admin", "addr", s.adminAddr, "error", err)
+	}
+}
+
+// Run serves until the context is cancelled, or until SIGINT or SIGTERM is
+// received, then shuts the server down, see Shutdown. It returns the errors of
+// serving and of the shutdown combined. The server is also shut down when
+// serving fails, e.g. when the listen address is in use.
+//
+// With WithServerInheritance, SIGHUP restarts the server without downtime: a
+// new process is started with the listeners, see Restart, and the server is
+// shut down once the new process is ready. The server continues to serve when
+// the restart fails.
+func (s *Server) Run(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	if s.inherit {
+		signal.Notify(signals, syscall.SIGHUP)
+	}
+	defer signal.Stop(signals)
+
+	result := make(chan error, 1)
+	go func() { result <- s.serve(ctx) }()
+
+	var (
+		serveErr error
+		served   bool
+	)
+	for done := false; !done; {
+		select {
+		case serveErr = <-result:
+			served, done = true, true
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				if _, err := s.Restart(ctx); err != nil {
+					s.logger.Error("msg", "failed to restart", "error", err)
+					continue
+				}
+				s.logger.Info("msg", "restarted, shutting down")
+			} else {
+				s.logger.Info("msg", "signal received, shutting down", "signal", sig.String())
+			}
+			done = true
+		case <-ctx.Done():
+			s.logger.Info("msg", "context done, shutting down", "reason", ctx.Err())
+			done = true
+		}
+	}
+
+	// The shutdown is bounded by the shutdown timeout, not by the cancelled
+	// context.
+	shutdownErr := s.Shutdown(context.Background())
+	if !served {
+		serveErr = <-result
+	}
+	return cerrors.Join(serveErr, shutdownErr)
+}
+
+// Shutdown stops the http server gracefully without interrupting any active
+// connections. It waits for the active connections to complete until the
+// earlier of the context's deadline and the shutdown timeout, and returns the
+// errors of the shutdown combined.
+func (s *Server) Shutdown(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.shutdownTimeout)
 	defer cancel()
 
-	// Stop each service.
-	s.mu.RLock()
-	for _, service := range s.services {
-		service.Shutdown()
-		s.logger.Info("msg", "service shutdown", "service", service.Name())
+	var errs []error
+
+	// Prevent the start of the services not yet started.
+	atomic.StoreInt32(&s.shuttingDown, 1)
+
+	// Fail readiness, so no new requests are routed to this server.
+	if s.health != nil {
+		s.health.Shutdown()
 	}
-	s.mu.RUnlock()
 
-	// Stop the http server.
+	// Stop the http server, waiting for the active requests to complete.
 	if err := s.server.Shutdown(ctx); err != nil {
-		s.logger.Error("msg", "failed to shutdown", "error", err.Error())
+		s.logger.Error("msg", "failed to shutdown", "error", err)
+		errs = append(errs, fmt.Errorf("failed to shutdown: %w", err))
+	}
+
+	// Stop each service, in reverse registration order, once any service
+	// being started has started.
+	s.lifecycle.Lock()
+	s.mu.RLock()
+	services := make([]Service, len(s.services))
+	copy(services, s.services)
+	s.mu.RUnlock()
+	for i := len(services) - 1; i >= 0; i-- {
+		if err := s.shutdownService(ctx, i, services[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.lifecycle.Unlock()
+
+	// Stop the redirect server.
+	if s.redirectAddr != "" {
+		if err := s.redirectServer.Shutdown(ctx); err != nil {
+			s.logger.Error("msg", "failed to shutdown redirect", "error", err)
+			errs = append(errs, fmt.Errorf("failed to shutdown redirect: %w", err))
+		}
+	}
+
+	// Stop the admin server.
+	if s.adminAddr != "" {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			s.logger.Error("msg", "failed to shutdown admin", "error", err)
+			errs = append(errs, fmt.Errorf("failed to shutdown admin: %w", err))
+		}
 	}
 
 	s.logger.Info("msg", "infra shutdown")
+	return cerrors.Join(errs...)
+}
+
+// startServices starts the services implementing Lifecycle, in registration
+// order. When a service fails to start, the services already started are shut
+// down. It returns http.ErrServerClosed, without starting the remaining
+// services, once the server is shutting down.
+func (s *Server) startServices(ctx context.Context) error {
+	s.lifecycle.Lock()
+	defer s.lifecycle.Unlock()
+
+	s.mu.RLock()
+	services := make([]Service, len(s.services))
+	copy(services, s.services)
+	s.mu.RUnlock()
+
+	for i, service := range services {
+		l, ok := service.(Lifecycle)
+		if !ok {
+			continue
+		}
+		s.mu.RLock()
+		started := s.started[i]
+		s.mu.RUnlock()
+		if started {
+			continue
+		}
+		if atomic.LoadInt32(&s.shuttingDown) == 1 {
+			return http.ErrServerClosed
+		}
+
+		if err := l.Start(ctx); err != nil {
+			s.logger.Error("msg", "failed to start service", "service", service.Name(), "error", err)
+			errs := []error{fmt.Errorf("failed to start %s service: %w", service.Name(), err)}
+			for j := i - 1; j >= 0; j-- {
+				if _, ok := services[j].(Lifecycle); ok {
+					errs = append(errs, s.shutdownService(ctx, j, services[j]))
+				}
+			}
+			return cerrors.Join(errs...)
+		}
+
+		s.mu.Lock()
+		s.started[i] = true
+		s.mu.Unlock()
+		s.logger.Info("msg", "service started", "service", service.Name())
+	}
+	return nil
+}
+
+// stopServices stops the started services implementing Lifecycle, in reverse
+// registration order, when serving fails to start.
+func (s *Server) stopServices() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	s.lifecycle.Lock()
+	defer s.lifecycle.Unlock()
+
+	s.mu.RLock()
+	services := make([]Service, len(s.services))
+	copy(services, s.services)
+	s.mu.RUnlock()
+
+	var errs []error
+	for i := len(services) - 1; i >= 0; i-- {
+		if _, ok := services[i].(Lifecycle); ok {
+			errs = append(errs, s.shutdownService(ctx, i, services[i]))
+		}
+	}
+	return cerrors.Join(errs...)
+}
+
+// shutdownService stops the i-th registered service, within the service
+// timeout when one is set. A service implementing Lifecycle is only stopped if
+// it was started.
+func (s *Server) shutdownService(ctx context.Context, i int, service Service) error {
+	switch svc := service.(type) {
+	case Lifecycle:
+		s.mu.Lock()
+		started := s.started[i]
+		delete(s.started, i)
+		s.mu.Unlock()
+		if !started {
+			return nil
+		}
+
+		if s.serviceTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.serviceTimeout)
+			defer cancel()
+		}
+		if err := svc.Shutdown(ctx); err != nil {
+			s.logger.Error("msg", "failed to shutdown service", "service", service.Name(), "error", err)
+			return fmt.Errorf("failed to shutdown %s service: %w", service.Name(), err)
+		}
+
+	case shutdowner:
+		svc.Shutdown()
+
+	default:
+		return nil
+	}
+	s.logger.Info("msg", "service shutdown", "service", service.Name())
+	return nil
 }
 
 // recoverPanics is middleware for recovering and reporting panics.
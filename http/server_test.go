@@ -15,16 +15,26 @@
 package http
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"arcadium.dev/core/config"
+	cerrors "arcadium.dev/core/errors"
+	"arcadium.dev/core/health"
 	"arcadium.dev/core/log"
 )
 
@@ -151,7 +161,7 @@ func TestServerServe(t *testing.T) {
 		go func() { wg.Done(); result <- s.Serve() }()
 		wg.Wait()
 
-		s.Shutdown()
+		s.Shutdown(context.Background())
 		err := <-result
 
 		if err != nil {
@@ -182,7 +192,7 @@ func TestServerServe(t *testing.T) {
 		go func() { wg.Done(); result <- s.Serve() }()
 		wg.Wait()
 
-		s.Shutdown()
+		s.Shutdown(context.Background())
 		err := <-result
 
 		if !m.shutdownCalled() {
@@ -194,6 +204,87 @@ func TestServerServe(t *testing.T) {
 	})
 }
 
+func TestServerAdmin(t *testing.T) {
+	t.Run("listen failure", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer l.Close()
+
+		s := NewServer(WithServerAddr("127.0.0.1:0"), WithServerAdminAddr(l.Addr().String()))
+		err = s.Serve()
+		expected := "failed to listen on " + l.Addr().String()
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("\nExpected error: %s\nActual error:   %s", expected, err)
+		}
+	})
+
+	t.Run("serve metrics", func(t *testing.T) {
+		b, logger := setupLogger(t)
+		reg := prometheus.NewRegistry()
+		s := NewServer(
+			WithServerAddr("127.0.0.1:4243"),
+			WithServerAdminAddr("127.0.0.1:4244"),
+			WithServerMetrics(WithMetricsRegisterer(reg)),
+			WithServerLogger(logger),
+		)
+		s.Register(&mockService{})
+
+		result := make(chan error, 1)
+		go func() { result <- s.Serve() }()
+
+		get := func(url string) (*http.Response, error) {
+			var resp *http.Response
+			var err error
+			for i := 0; i < 100; i++ {
+				if resp, err = http.Get(url); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			return resp, err
+		}
+
+		resp, err := get("http://127.0.0.1:4243/foo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		resp.Body.Close()
+
+		resp, err = get("http://127.0.0.1:4244/metrics")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `http_request_count{method="GET",path="/foo",service="mockService",status="200"} 1`) {
+			t.Errorf("Unexpected metrics: %d %s", resp.StatusCode, body)
+		}
+
+		// Metrics are not served by the public listener.
+		resp, err = get("http://127.0.0.1:4243/metrics")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Unexpected status: %d", resp.StatusCode)
+		}
+
+		s.Shutdown(context.Background())
+		if err := <-result; err != nil {
+			t.Errorf("Unexpected err: %s", err)
+		}
+		if _, err := http.Get("http://127.0.0.1:4244/metrics"); err == nil {
+			t.Error("Expected the admin listener to be closed")
+		}
+		if !strings.Contains(b.Index(0), "admin_addr=127.0.0.1:4244") {
+			t.Errorf("Unexpected log: %s", b.Index(0))
+		}
+	})
+}
+
 func TestServerRecoverPanics(t *testing.T) {
 	b, logger := setupLogger(t)
 	m := &mockService{}
@@ -344,3 +435,321 @@ func (m *mockService) shutdownCalled() bool {
 	defer m.mu.RUnlock()
 	return m.shutdown
 }
+
+func TestServerHealth(t *testing.T) {
+	h, err := health.New()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	h.Register("check", health.CheckerFunc(func(context.Context) error { return nil }))
+
+	s := NewServer(WithServerHealth(h))
+
+	ready := func() int {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Result().StatusCode
+	}
+
+	if status := ready(); status != http.StatusOK {
+		t.Errorf("\nExpected status: %d\nActual status:   %d", http.StatusOK, status)
+	}
+
+	s.Shutdown(context.Background())
+
+	if status := ready(); status != http.StatusServiceUnavailable {
+		t.Errorf("\nExpected status: %d\nActual status:   %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestServerRun(t *testing.T) {
+	waitServing := func(t *testing.T, url string) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			if resp, err := http.Get(url); err == nil {
+				resp.Body.Close()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Server not serving %s", url)
+	}
+
+	t.Run("context cancelled", func(t *testing.T) {
+		m := &mockService{}
+		s := NewServer(WithServerAddr("127.0.0.1:4245"))
+		s.Register(m)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan error, 1)
+		go func() { result <- s.Run(ctx) }()
+		waitServing(t, "http://127.0.0.1:4245/foo")
+
+		cancel()
+		if err := <-result; err != nil {
+			t.Errorf("Unexpected err: %s", err)
+		}
+		if !m.shutdownCalled() {
+			t.Error("Expected shutdown to be called")
+		}
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		b, logger := setupLogger(t)
+		m := &mockService{}
+		s := NewServer(WithServerAddr("127.0.0.1:4245"), WithServerLogger(logger))
+		s.Register(m)
+
+		result := make(chan error, 1)
+		go func() { result <- s.Run(context.Background()) }()
+		waitServing(t, "http://127.0.0.1:4245/foo")
+
+		if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if err := <-result; err != nil {
+			t.Errorf("Unexpected err: %s", err)
+		}
+		if !m.shutdownCalled() {
+			t.Error("Expected shutdown to be called")
+		}
+
+		found := false
+		for i := 0; i < b.Len(); i++ {
+			if strings.Contains(b.Index(i), `msg="signal received, shutting down" signal=terminated`) {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("Expected the signal to be logged")
+		}
+	})
+
+	t.Run("serve failure", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer l.Close()
+
+		m := &mockService{}
+		s := NewServer(WithServerAddr(l.Addr().String()))
+		s.Register(m)
+
+		err = s.Run(context.Background())
+		expected := "failed to listen on " + l.Addr().String()
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("\nExpected error: %s\nActual error:   %s", expected, err)
+		}
+		if !m.shutdownCalled() {
+			t.Error("Expected shutdown to be called")
+		}
+	})
+}
+
+func TestServerShutdownDeadline(t *testing.T) {
+	s := NewServer(WithServerAddr("127.0.0.1:4246"))
+
+	release := make(chan struct{})
+	defer close(release)
+	s.router.HandleFunc("/block", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	result := make(chan error, 1)
+	go func() { result <- s.Serve() }()
+
+	for i := 0; i < 100; i++ {
+		if c, err := net.Dial("tcp", "127.0.0.1:4246"); err == nil {
+			c.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	go http.Get("http://127.0.0.1:4246/block")
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := s.Shutdown(ctx)
+	if time.Since(start) > time.Second {
+		t.Error("Expected the caller's deadline to bound the shutdown")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "failed to shutdown") {
+		t.Errorf("Unexpected err: %v", err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("Unexpected err: %s", err)
+	}
+}
+
+type (
+	lifecycleService struct {
+		name        string
+		startErr    error
+		shutdownErr error
+		startDelay  time.Duration
+		block       bool
+		events      *events
+	}
+
+	events struct {
+		mu     sync.Mutex
+		events []string
+	}
+)
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *events) String() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return strings.Join(e.events, ",")
+}
+
+func (l *lifecycleService) Register(*mux.Router) {}
+
+func (l *lifecycleService) Name() string {
+	return l.name
+}
+
+func (l *lifecycleService) Start(context.Context) error {
+	time.Sleep(l.startDelay)
+	l.events.add("start " + l.name)
+	return l.startErr
+}
+
+func (l *lifecycleService) Shutdown(ctx context.Context) error {
+	l.events.add("shutdown " + l.name)
+	if l.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return l.shutdownErr
+}
+
+func TestServerLifecycle(t *testing.T) {
+	t.Run("order", func(t *testing.T) {
+		e := &events{}
+		m := &mockService{}
+		s := NewServer()
+		s.Register(&lifecycleService{name: "a", events: e}, m, &lifecycleService{name: "b", events: e})
+
+		if err := s.startServices(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		expected := "start a,start b,shutdown b,shutdown a"
+		if e.String() != expected {
+			t.Errorf("\nExpected events: %s\nActual events:   %s", expected, e.String())
+		}
+		if !m.shutdownCalled() {
+			t.Error("Expected shutdown to be called")
+		}
+	})
+
+	t.Run("start failure", func(t *testing.T) {
+		e := &events{}
+		s := NewServer(WithServerAddr("127.0.0.1:0"))
+		s.Register(
+			&lifecycleService{name: "a", events: e},
+			&lifecycleService{name: "b", events: e, startErr: errors.New("boom")},
+			&lifecycleService{name: "c", events: e},
+		)
+
+		err := s.Serve()
+		if err == nil || err.Error() != "failed to start b service: boom" {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		expected := "start a,start b,shutdown a"
+		if e.String() != expected {
+			t.Errorf("\nExpected events: %s\nActual events:   %s", expected, e.String())
+		}
+	})
+
+	t.Run("shutdown during start", func(t *testing.T) {
+		e := &events{}
+		s := NewServer(WithServerAddr("127.0.0.1:0"))
+		s.Register(
+			&lifecycleService{name: "a", events: e, startDelay: 100 * time.Millisecond},
+			&lifecycleService{name: "b", events: e},
+		)
+
+		result := make(chan error, 1)
+		go func() { result <- s.Serve() }()
+		time.Sleep(20 * time.Millisecond)
+
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if err := <-result; err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		expected := "start a,shutdown a"
+		if e.String() != expected {
+			t.Errorf("\nExpected events: %s\nActual events:   %s", expected, e.String())
+		}
+	})
+
+	t.Run("listen failure", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer l.Close()
+
+		e := &events{}
+		s := NewServer(WithServerAddr(l.Addr().String()))
+		s.Register(&lifecycleService{name: "a", events: e})
+
+		if err := s.Serve(); err == nil {
+			t.Error("Expected an error")
+		}
+
+		expected := "start a,shutdown a"
+		if e.String() != expected {
+			t.Errorf("\nExpected events: %s\nActual events:   %s", expected, e.String())
+		}
+	})
+
+	t.Run("shutdown failures", func(t *testing.T) {
+		e := &events{}
+		s := NewServer(WithServerServiceTimeout(20 * time.Millisecond))
+		s.Register(
+			&lifecycleService{name: "a", events: e, shutdownErr: cerrors.ErrInternal},
+			&lifecycleService{name: "b", events: e, block: true},
+		)
+
+		if err := s.startServices(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		start := time.Now()
+		err := s.Shutdown(context.Background())
+		if time.Since(start) > time.Second {
+			t.Error("Expected the service timeout to bound the shutdown")
+		}
+
+		expected := "failed to shutdown b service: context deadline exceeded; failed to shutdown a service: internal error"
+		if err == nil || err.Error() != expected {
+			t.Errorf("\nExpected error: %s\nActual error:   %v", expected, err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, cerrors.ErrInternal) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prom provides helpers for the prometheus collectors of the packages
+// of this module.
+package prom // import "arcadium.dev/core/internal/prom"
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Register registers the collector with the registerer. When a collector of
+// the same type is already registered in its place, e.g. by another server or
+// logger, that collector is returned instead so the two share it. On error,
+// the given collector is returned.
+func Register(reg prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) && reflect.TypeOf(are.ExistingCollector) == reflect.TypeOf(c) {
+			return are.ExistingCollector, nil
+		}
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prom_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"arcadium.dev/core/internal/prom"
+)
+
+func TestRegister(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	opts := prometheus.CounterOpts{Name: "test_total", Help: "Test counter."}
+
+	first := prometheus.NewCounter(opts)
+	c, err := prom.Register(reg, first)
+	if err != nil || c != first {
+		t.Errorf("\nExpected: %+v, <nil>\nActual:   %+v, %s", first, c, err)
+	}
+
+	// A collector of the same type is replaced by the registered one.
+	c, err = prom.Register(reg, prometheus.NewCounter(opts))
+	if err != nil || c != first {
+		t.Errorf("\nExpected: %+v, <nil>\nActual:   %+v, %s", first, c, err)
+	}
+
+	// A collector of another type fails, and is returned.
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_total", Help: "Test counter."})
+	c, err = prom.Register(reg, g)
+	if err == nil || c != g {
+		t.Errorf("\nExpected: %+v, error\nActual:   %+v, %v", g, c, err)
+	}
+}
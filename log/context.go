@@ -0,0 +1,295 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+
+	"github.com/go-kit/log"
+)
+
+const (
+	// RequestIDKey is the key of the request ID extracted from a context.
+	RequestIDKey = "request_id"
+
+	// TraceIDKey is the key of the trace ID extracted from a context.
+	TraceIDKey = traceIDKey
+
+	// SpanIDKey is the key of the span ID extracted from a context.
+	SpanIDKey = spanIDKey
+
+	// UserIDKey is the key of the user ID extracted from a context.
+	UserIDKey = "user_id"
+)
+
+type (
+	// Extractor returns a value to be logged from the context, and whether the
+	// context holds the value.
+	Extractor func(ctx context.Context) (interface{}, bool)
+
+	registeredExtractor struct {
+		key     string
+		extract Extractor
+	}
+
+	// contextCarrier is the key of the pair which carries the context of an
+	// entry logged by one of the Context methods through the go-kit loggers.
+	contextCarrier struct{}
+
+	// contextLogger is a go-kit logger which removes the context carried by an
+	// entry, and adds the values extracted from the context. The context is
+	// kept when there are hooks, for the hook logger.
+	contextLogger struct {
+		next  log.Logger
+		hooks *hooks
+	}
+)
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = []registeredExtractor{
+		{key: RequestIDKey, extract: stringExtractor(requestIDContextKey)},
+		{key: TraceIDKey, extract: traceIDExtractor},
+		{key: SpanIDKey, extract: spanIDExtractor},
+		{key: UserIDKey, extract: stringExtractor(userIDContextKey)},
+	}
+
+	fallbackMu sync.RWMutex
+	fallback   func() Logger
+)
+
+// RegisterExtractor registers an extractor for the given key. The values
+// extracted are added to the entries logged by the Context methods, unless
+// the entry already has the key. Registering a key again replaces its
+// extractor, and a nil extractor removes it. The request_id, trace_id,
+// span_id and user_id keys are registered by default.
+func RegisterExtractor(key string, extract Extractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	for i, e := range extractors {
+		if e.key != key {
+			continue
+		}
+		if extract == nil {
+			extractors = append(extractors[:i:i], extractors[i+1:]...)
+		} else {
+			extractors[i].extract = extract
+		}
+		return
+	}
+	if extract != nil {
+		extractors = append(extractors, registeredExtractor{key: key, extract: extract})
+	}
+}
+
+// SetContextFallback sets the function providing the logger returned by
+// LoggerFromContext when the context holds no logger, e.g.
+//
+//	log.SetContextFallback(func() log.Logger { return log.DefaultLogger })
+//
+// A nil function restores the default, a logger with the FormatNop format.
+func SetContextFallback(f func() Logger) {
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+
+	fallback = f
+}
+
+// NewContextWithRequestID returns a new context with the given request ID.
+func NewContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestIDFromContext returns the request ID held by the context, or an empty
+// string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// NewContextWithUserID returns a new context with the given user ID.
+func NewContextWithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDContextKey, id)
+}
+
+// UserIDFromContext returns the user ID held by the context, or an empty
+// string.
+func UserIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIDContextKey).(string)
+	return id
+}
+
+// TraceContext logs a trace level message with the values extracted from the
+// context.
+func (l Logger) TraceContext(ctx context.Context, kv ...interface{}) {
+	l.logContext(ctx, LevelTrace, kv)
+}
+
+// TraceContext logs a trace level message to the logger from the context.
+func TraceContext(ctx context.Context, kv ...interface{}) {
+	LoggerFromContext(ctx).TraceContext(ctx, kv...)
+}
+
+// DebugContext logs a debug level message with the values extracted from the
+// context.
+func (l Logger) DebugContext(ctx context.Context, kv ...interface{}) {
+	l.logContext(ctx, LevelDebug, kv)
+}
+
+// DebugContext logs a debug level message to the logger from the context.
+func DebugContext(ctx context.Context, kv ...interface{}) {
+	LoggerFromContext(ctx).DebugContext(ctx, kv...)
+}
+
+// InfoContext logs an info level message with the values extracted from the
+// context.
+func (l Logger) InfoContext(ctx context.Context, kv ...interface{}) {
+	l.logContext(ctx, LevelInfo, kv)
+}
+
+// InfoContext logs an info level message to the logger from the context.
+func InfoContext(ctx context.Context, kv ...interface{}) {
+	LoggerFromContext(ctx).InfoContext(ctx, kv...)
+}
+
+// WarnContext logs a warn level message with the values extracted from the
+// context.
+func (l Logger) WarnContext(ctx context.Context, kv ...interface{}) {
+	l.logContext(ctx, LevelWarn, kv)
+}
+
+// WarnContext logs a warn level message to the logger from the context.
+func WarnContext(ctx context.Context, kv ...interface{}) {
+	LoggerFromContext(ctx).WarnContext(ctx, kv...)
+}
+
+// ErrorContext logs an error level message with the values extracted from the
+// context.
+func (l Logger) ErrorContext(ctx context.Context, kv ...interface{}) {
+	l.logContext(ctx, LevelError, kv)
+}
+
+// ErrorContext logs an error level message to the logger from the context.
+func ErrorContext(ctx context.Context, kv ...interface{}) {
+	LoggerFromContext(ctx).ErrorContext(ctx, kv...)
+}
+
+// logContext logs the key/value pairs at the given level, carrying the
+// context to the context logger.
+func (l Logger) logContext(ctx context.Context, lvl Level, kv []interface{}) {
+	kv = kv[:len(kv):len(kv)]
+	if len(kv)%2 != 0 {
+		kv = append(kv, log.ErrMissingValue)
+	}
+	l.log(lvl, append(kv, contextCarrier{}, ctx))
+}
+
+func newContextLogger(next log.Logger, h *hooks) log.Logger {
+	return contextLogger{next: next, hooks: h}
+}
+
+func (l contextLogger) Log(kv ...interface{}) error {
+	n := len(kv)
+	if n < 2 || kv[n-2] != (contextCarrier{}) {
+		return l.next.Log(kv...)
+	}
+	ctx, _ := kv[n-1].(context.Context)
+
+	extractorsMu.RLock()
+	out := make([]interface{}, 0, n+2*len(extractors))
+	out = append(out, kv[:n-2]...)
+	if ctx != nil {
+		for _, e := range extractors {
+			if hasKey(out, e.key) {
+				continue
+			}
+			if v, ok := e.extract(ctx); ok {
+				out = append(out, e.key, v)
+			}
+		}
+	}
+	extractorsMu.RUnlock()
+	if l.hooks != nil {
+		out = append(out, kv[n-2:]...)
+	}
+	return l.next.Log(out...)
+}
+
+// contextOf removes the context carried by the key/value pairs, if any, and
+// returns it along with the remaining pairs.
+func contextOf(kv []interface{}) (context.Context, []interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i] != (contextCarrier{}) {
+			continue
+		}
+		ctx, ok := kv[i+1].(context.Context)
+		if !ok || ctx == nil {
+			ctx = context.Background()
+		}
+		out := make([]interface{}, 0, len(kv)-2)
+		out = append(out, kv[:i]...)
+		return ctx, append(out, kv[i+2:]...)
+	}
+	return context.Background(), kv
+}
+
+// hasKey reports whether the key/value pairs have the given key.
+func hasKey(kv []interface{}, key string) bool {
+	for i := 0; i < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok && k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// contextFallback returns the logger used when a context holds no logger.
+func contextFallback() Logger {
+	fallbackMu.RLock()
+	f := fallback
+	fallbackMu.RUnlock()
+
+	if f != nil {
+		return f()
+	}
+	logger, _ := New(WithFormat(FormatNop))
+	return logger
+}
+
+func stringExtractor(key contextKey) Extractor {
+	return func(ctx context.Context) (interface{}, bool) {
+		s, ok := ctx.Value(key).(string)
+		return s, ok && s != ""
+	}
+}
+
+func traceIDExtractor(ctx context.Context) (interface{}, bool) {
+	sc, ok := SpanFromContext(ctx)
+	if !ok || sc.TraceID == [16]byte{} {
+		return nil, false
+	}
+	return hex.EncodeToString(sc.TraceID[:]), true
+}
+
+func spanIDExtractor(ctx context.Context) (interface{}, bool) {
+	sc, ok := SpanFromContext(ctx)
+	if !ok || sc.SpanID == [8]byte{} {
+		return nil, false
+	}
+	return hex.EncodeToString(sc.SpanID[:]), true
+}
@@ -0,0 +1,128 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"arcadium.dev/core/log"
+)
+
+type tenantKey struct{}
+
+func TestContextMethods(t *testing.T) {
+	ctx := log.NewContextWithRequestID(context.Background(), "req-1")
+	ctx = log.NewContextWithUserID(ctx, "alice")
+	ctx = log.NewContextWithSpan(ctx, log.SpanContext{TraceID: [16]byte{15: 1}, SpanID: [8]byte{7: 2}})
+
+	t.Run("extracted values", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp(), log.WithLevel(log.LevelTrace))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.TraceContext(ctx, "msg", "trace")
+		l.DebugContext(ctx, "msg", "debug")
+		l.InfoContext(ctx, "msg", "info", "user_id", "bob")
+		l.WarnContext(context.Background(), "msg", "warn")
+		l.With("request_id", "req-0").ErrorContext(ctx, "msg", "error")
+		l.Info("msg", "plain")
+
+		ids := "trace_id=00000000000000000000000000000001 span_id=0000000000000002"
+		expected := []string{
+			"level=trace msg=trace request_id=req-1 " + ids + " user_id=alice\n",
+			"level=debug msg=debug request_id=req-1 " + ids + " user_id=alice\n",
+			"level=info msg=info user_id=bob request_id=req-1 " + ids + "\n",
+			"level=warn msg=warn\n",
+			"level=error request_id=req-0 msg=error " + ids + " user_id=alice\n",
+			"level=info msg=plain\n",
+		}
+		checkBuffer(t, b, expected)
+	})
+
+	t.Run("registered extractor", func(t *testing.T) {
+		log.RegisterExtractor("tenant", func(ctx context.Context) (interface{}, bool) {
+			v, ok := ctx.Value(tenantKey{}).(string)
+			return v, ok
+		})
+		defer log.RegisterExtractor("tenant", nil)
+
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.InfoContext(context.WithValue(context.Background(), tenantKey{}, "arcadium"), "msg", "hello")
+		log.RegisterExtractor("tenant", nil)
+		l.InfoContext(context.WithValue(context.Background(), tenantKey{}, "arcadium"), "msg", "hello")
+
+		expected := []string{
+			"level=info msg=hello tenant=arcadium\n",
+			"level=info msg=hello\n",
+		}
+		checkBuffer(t, b, expected)
+	})
+
+	t.Run("hooks receive the context", func(t *testing.T) {
+		ctxs := make(chan context.Context, 1)
+		hook := log.HookFunc(func(ctx context.Context, e log.Entry) error {
+			ctxs <- ctx
+			return nil
+		})
+
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp(), log.WithHook(hook, log.LevelInfo))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.InfoContext(ctx, "msg", "hello")
+
+		select {
+		case hctx := <-ctxs:
+			if log.RequestIDFromContext(hctx) != "req-1" {
+				t.Error("Unexpected hook context")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the hook to fire")
+		}
+		expected := "level=info msg=hello request_id=req-1 " +
+			"trace_id=00000000000000000000000000000001 span_id=0000000000000002 user_id=alice\n"
+		checkBuffer(t, b, []string{expected})
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		log.InfoContext(ctx, "msg", "dropped")
+
+		log.SetContextFallback(func() log.Logger { return l })
+		defer log.SetContextFallback(nil)
+
+		log.InfoContext(context.Background(), "msg", "fallback")
+		if log.LoggerFromContext(context.Background()) != l {
+			t.Error("Unexpected logger from context")
+		}
+		checkBuffer(t, b, []string{"level=info msg=fallback\n"})
+	})
+}
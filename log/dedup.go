@@ -0,0 +1,122 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+const (
+	// DropDuplicate counts entries suppressed as duplicates, see the
+	// WithDeduplication option.
+	DropDuplicate = "duplicate"
+
+	// repeatedKey is the key of the repeat count added to a summary entry.
+	repeatedKey = "repeated"
+)
+
+type (
+	// dedupLogger is a go-kit logger which suppresses entries identical to the
+	// previous entry within a time window. When the window closes, or a
+	// different entry is logged, the last suppressed entry is logged with a
+	// count of the repeats. Entries are never reordered.
+	dedupLogger struct {
+		next    log.Logger
+		window  time.Duration
+		keys    []string
+		metrics *metrics
+
+		mu       sync.Mutex
+		key      string
+		start    time.Time
+		repeated int
+		last     []interface{}
+		timer    *time.Timer
+	}
+)
+
+func newDedupLogger(next log.Logger, window time.Duration, keys []string, m *metrics) *dedupLogger {
+	return &dedupLogger{
+		next:    next,
+		window:  window,
+		keys:    keys,
+		metrics: m,
+	}
+}
+
+func (l *dedupLogger) Log(kv ...interface{}) error {
+	key := l.dedupKey(kv)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if key == l.key && now.Sub(l.start) < l.window {
+		l.repeated++
+		l.last = kv
+		l.metrics.drop(DropDuplicate)
+		if l.timer == nil {
+			l.timer = time.AfterFunc(l.window-now.Sub(l.start), l.flush)
+		}
+		return nil
+	}
+
+	l.summarize()
+	l.key, l.start = key, now
+	return l.next.Log(kv...)
+}
+
+// flush logs the summary of the suppressed entries, if any.
+func (l *dedupLogger) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.summarize()
+}
+
+// summarize logs the last suppressed entry with the repeat count, and closes
+// the window. The lock must be held.
+func (l *dedupLogger) summarize() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	if l.repeated > 0 {
+		kv := make([]interface{}, 0, len(l.last)+2)
+		kv = append(kv, l.last...)
+		l.next.Log(append(kv, repeatedKey, l.repeated)...)
+	}
+	l.key, l.repeated, l.last = "", 0, nil
+}
+
+// dedupKey identifies an entry by its level, logger name, msg and the selected
+// keys.
+func (l *dedupLogger) dedupKey(kv []interface{}) string {
+	var b strings.Builder
+	b.WriteString(levelOf(kv))
+	b.WriteByte(0)
+	b.WriteString(valueOf(kv, nameKey))
+	b.WriteByte(0)
+	b.WriteString(valueOf(kv, "msg"))
+	for _, k := range l.keys {
+		b.WriteByte(0)
+		b.WriteString(valueOf(kv, k))
+	}
+	return b.String()
+}
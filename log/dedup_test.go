@@ -0,0 +1,152 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"arcadium.dev/core/log"
+)
+
+func TestDeduplication(t *testing.T) {
+	t.Run("collapse duplicates, keep order", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		reg := prometheus.NewRegistry()
+		l, err := log.New(
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+			log.WithMetrics(reg),
+			log.WithDeduplication(time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		for i := 1; i <= 3; i++ {
+			l.Info("msg", "ping failed, retrying...", "count", i)
+		}
+		l.Info("msg", "connected to database")
+		l.Warn("msg", "connected to database")
+
+		expected := []string{
+			"level=info msg=\"ping failed, retrying...\" count=1\n",
+			"level=info msg=\"ping failed, retrying...\" count=3 repeated=2\n",
+			"level=info msg=\"connected to database\"\n",
+			"level=warn msg=\"connected to database\"\n",
+		}
+		checkBuffer(t, b, expected)
+
+		metrics := `
+# HELP log_dropped_entries_total Total number of log entries dropped or suppressed, by reason.
+# TYPE log_dropped_entries_total counter
+log_dropped_entries_total{reason="duplicate"} 2
+`
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(metrics), "log_dropped_entries_total"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("selected keys", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+			log.WithDeduplication(time.Hour, "addr"),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Info("msg", "dial failed", "addr", "a")
+		l.Info("msg", "dial failed", "addr", "b")
+		l.Info("msg", "dial failed", "addr", "b")
+		l.Flush()
+
+		expected := []string{
+			"level=info msg=\"dial failed\" addr=a\n",
+			"level=info msg=\"dial failed\" addr=b\n",
+			"level=info msg=\"dial failed\" addr=b repeated=1\n",
+		}
+		checkBuffer(t, b, expected)
+	})
+
+	t.Run("named loggers", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+			log.WithDeduplication(time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Named("sql").Error("msg", "timeout")
+		l.Named("http").Error("msg", "timeout")
+		l.Flush()
+
+		expected := []string{
+			"level=error logger=sql msg=timeout\n",
+			"level=error logger=http msg=timeout\n",
+		}
+		checkBuffer(t, b, expected)
+	})
+
+	t.Run("window closes", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+			log.WithDeduplication(20*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Error("msg", "boom")
+		l.Error("msg", "boom")
+
+		deadline := time.Now().Add(5 * time.Second)
+		for b.Len() < 2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		l.Error("msg", "boom")
+
+		expected := []string{
+			"level=error msg=boom\n",
+			"level=error msg=boom repeated=1\n",
+			"level=error msg=boom\n",
+		}
+		checkBuffer(t, b, expected)
+	})
+}
+
+func checkBuffer(t *testing.T, b *log.StringBuffer, expected []string) {
+	t.Helper()
+
+	if b.Len() != len(expected) {
+		t.Fatalf("Unexpected buffer length: %d", b.Len())
+	}
+	for i, e := range expected {
+		if b.Index(i) != e {
+			t.Errorf("\nExpected %sActual:  %s", e, b.Index(i))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"github.com/go-kit/log"
+
+	cerrors "arcadium.dev/core/errors"
+)
+
+// Keys of the structured representation of an error value.
+const (
+	errorMessageKey  = "message"
+	errorChainKey    = "chain"
+	errorSentinelKey = "sentinel"
+	errorStackKey    = "stack"
+)
+
+type (
+	// errorLogger is a go-kit logger which replaces each error value with a
+	// structured representation: the message, the messages of the unwrapped
+	// chain, the sentinel from the core errors package, and the stack trace
+	// when the error carries one. When nested, the error is rendered as a
+	// single object, otherwise as flattened keys, e.g. err.chain.0.
+	errorLogger struct {
+		next   log.Logger
+		nested bool
+	}
+
+	// stackTracer is implemented by errors which carry a stack trace, see
+	// errors.WithStack.
+	stackTracer interface {
+		StackTrace() []uintptr
+	}
+)
+
+func newErrorLogger(next log.Logger, nested bool) log.Logger {
+	return errorLogger{next: next, nested: nested}
+}
+
+func (l errorLogger) Log(kv ...interface{}) error {
+	var out []interface{}
+	for i := 1; i < len(kv); i += 2 {
+		err, ok := kv[i].(error)
+		if !ok || err == nil {
+			if out != nil {
+				out = append(out, kv[i-1], kv[i])
+			}
+			continue
+		}
+		if out == nil {
+			// Copy the key/value pairs, they may be shared with other loggers.
+			out = make([]interface{}, 0, len(kv))
+			out = append(out, kv[:i-1]...)
+		}
+		out = l.appendError(out, kv[i-1], err)
+	}
+	if out == nil {
+		return l.next.Log(kv...)
+	}
+	if len(kv)%2 != 0 {
+		out = append(out, kv[len(kv)-1])
+	}
+	return l.next.Log(out...)
+}
+
+// appendError appends the representation of err, with the given key, to kv.
+func (l errorLogger) appendError(kv []interface{}, key interface{}, err error) []interface{} {
+	chain := errorChain(err)
+	sentinel := cerrors.Sentinel(err)
+	stack := errorStack(err)
+
+	if l.nested {
+		v := map[string]interface{}{errorMessageKey: err.Error()}
+		if len(chain) > 0 {
+			v[errorChainKey] = chain
+		}
+		if sentinel != nil {
+			v[errorSentinelKey] = sentinel.Error()
+		}
+		if len(stack) > 0 {
+			v[errorStackKey] = stack
+		}
+		return append(kv, key, v)
+	}
+
+	prefix := fmt.Sprint(key)
+	kv = append(kv, key, err.Error())
+	for i, msg := range chain {
+		kv = append(kv, prefix+"."+errorChainKey+"."+strconv.Itoa(i), msg)
+	}
+	if sentinel != nil {
+		kv = append(kv, prefix+"."+errorSentinelKey, sentinel.Error())
+	}
+	for i, frame := range stack {
+		kv = append(kv, prefix+"."+errorStackKey+"."+strconv.Itoa(i), frame)
+	}
+	return kv
+}
+
+// errorChain returns the messages of the errors wrapped by err, outermost
+// first. Wrappers which do not change the message are skipped.
+func errorChain(err error) []string {
+	var chain []string
+	prev := err.Error()
+	for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+		if msg := e.Error(); msg != prev {
+			chain = append(chain, msg)
+			prev = msg
+		}
+	}
+	return chain
+}
+
+// errorStack returns the frames of the first stack trace found in the chain
+// of err, formatted as "function file:line".
+func errorStack(err error) []string {
+	var st stackTracer
+	if !errors.As(err, &st) {
+		return nil
+	}
+	var stack []string
+	frames := runtime.CallersFrames(st.StackTrace())
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+	return stack
+}
@@ -0,0 +1,98 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	cerrors "arcadium.dev/core/errors"
+	"arcadium.dev/core/log"
+)
+
+func TestErrorValues(t *testing.T) {
+	wrapped := fmt.Errorf("failed to load user: %w", fmt.Errorf("query failed: %w", cerrors.ErrNotFound))
+
+	t.Run("logfmt", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Error("msg", "request failed", "error", wrapped, "id", 42)
+		l.Error("error", errors.New("plain"))
+
+		expected := []string{
+			"level=error msg=\"request failed\" " +
+				"error=\"failed to load user: query failed: not found\" " +
+				"error.chain.0=\"query failed: not found\" " +
+				"error.chain.1=\"not found\" " +
+				"error.sentinel=\"not found\" id=42\n",
+			"level=error error=plain\n",
+		}
+		checkBuffer(t, b, expected)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp(), log.WithFormat(log.FormatJSON))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Error("msg", "request failed", "error", wrapped)
+
+		expected := `{"error":{"chain":["query failed: not found","not found"],` +
+			`"message":"failed to load user: query failed: not found","sentinel":"not found"},` +
+			`"level":"error","msg":"request failed"}` + "\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:   %s", expected, b.Index(0))
+		}
+	})
+
+	t.Run("stack", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithOutput(b), log.WithoutTimestamp(), log.WithFormat(log.FormatJSON))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Error("error", fmt.Errorf("outer: %w", cerrors.WithStack(cerrors.ErrInternal)))
+
+		var entry struct {
+			Error struct {
+				Message  string   `json:"message"`
+				Chain    []string `json:"chain"`
+				Sentinel string   `json:"sentinel"`
+				Stack    []string `json:"stack"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(b.Index(0)), &entry); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		e := entry.Error
+		if e.Message != "outer: internal error" || len(e.Chain) != 1 || e.Chain[0] != "internal error" ||
+			e.Sentinel != "internal error" {
+			t.Errorf("Unexpected error: %+v", e)
+		}
+		if len(e.Stack) == 0 || !strings.Contains(e.Stack[0], "TestErrorValues") {
+			t.Errorf("Unexpected stack: %+v", e.Stack)
+		}
+	})
+}
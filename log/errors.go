@@ -28,4 +28,20 @@ var (
 	// ErrInvalidOutput will be returned when the output writer given to WithOuput
 	// is nil.
 	ErrInvalidOutput = errors.New("invalid output")
+
+	// ErrMissingExporter will be returned when the FormatOTLP format is given
+	// without the WithOTLPExporter option.
+	ErrMissingExporter = errors.New("missing exporter")
+
+	// ErrInvalidNetwork will be returned when the network given to
+	// NewSyslogWriter is not one of udp, tcp or tls.
+	ErrInvalidNetwork = errors.New("invalid network")
+
+	// ErrInvalidSyslogFormat will be returned when the format given to the
+	// WithSyslogFormat option is invalid.
+	ErrInvalidSyslogFormat = errors.New("invalid syslog format")
+
+	// ErrInvalidSyslogFacility will be returned when the facility given to the
+	// WithSyslogFacility option is invalid.
+	ErrInvalidSyslogFacility = errors.New("invalid syslog facility")
 )
@@ -0,0 +1,274 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"arcadium.dev/core/errors"
+)
+
+const (
+	defaultHookQueueSize = 1024
+)
+
+type (
+	// Hook is invoked for each log entry at or above the level given to the
+	// WithHook option. Entries logged by the Context methods are given the
+	// values of their context, others the background context. As hooks are
+	// run asynchronously, in the order they were given, by a single goroutine
+	// per logger, the context given to a hook is never cancelled and has no
+	// deadline: the entry outlives, e.g., the request which logged it. An
+	// error returned by a hook is discarded.
+	Hook interface {
+		Fire(ctx context.Context, entry Entry) error
+	}
+
+	// HookFunc is an adapter allowing a function to be used as a Hook.
+	HookFunc func(ctx context.Context, entry Entry) error
+
+	// Entry is a log entry given to a Hook.
+	Entry struct {
+		// Time is the time the entry was logged.
+		Time time.Time
+
+		// Level is the level of the entry.
+		Level Level
+
+		// Fields holds all of the key/value pairs of the entry, including the
+		// level and those added with With.
+		Fields map[string]interface{}
+	}
+
+	// hooks queues entries for the registered hooks.
+	hooks struct {
+		// pending counts the entries queued, or being handled by the hooks. It
+		// is first to guarantee 64-bit alignment for atomic access.
+		pending int64
+		closed  int32
+
+		hooks   []registeredHook
+		level   Level
+		queue   chan hookEntry
+		done    chan struct{}
+		stopped chan struct{}
+		metrics *metrics
+	}
+
+	registeredHook struct {
+		hook  Hook
+		level Level
+	}
+
+	hookEntry struct {
+		ctx   context.Context
+		entry Entry
+	}
+
+	// detachedContext holds the values of its parent, without its deadline
+	// and cancellation.
+	detachedContext struct {
+		parent context.Context
+	}
+
+	// hookLogger is a go-kit logger which sends each entry to the hooks before
+	// passing it to the next logger.
+	hookLogger struct {
+		next  log.Logger
+		hooks *hooks
+	}
+)
+
+// Fire calls f(ctx, entry).
+func (f HookFunc) Fire(ctx context.Context, entry Entry) error {
+	return f(ctx, entry)
+}
+
+func newHooks(registered []registeredHook, size int, m *metrics) *hooks {
+	if len(registered) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = defaultHookQueueSize
+	}
+	h := &hooks{
+		hooks:   registered,
+		level:   LevelInvalid,
+		queue:   make(chan hookEntry, size),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		metrics: m,
+	}
+	for _, r := range registered {
+		if r.level < h.level {
+			h.level = r.level
+		}
+	}
+	go h.run()
+	return h
+}
+
+// enqueue queues the entry for the hooks, unless the queue is full or the
+// hooks are closed. It reports whether the entry was queued.
+func (h *hooks) enqueue(ctx context.Context, entry Entry) bool {
+	if entry.Level < h.level || entry.Level == LevelInvalid {
+		return true
+	}
+	if atomic.LoadInt32(&h.closed) == 1 {
+		return false
+	}
+	atomic.AddInt64(&h.pending, 1)
+	select {
+	case h.queue <- hookEntry{ctx: detachedContext{parent: ctx}, entry: entry}:
+		return true
+	default:
+		atomic.AddInt64(&h.pending, -1)
+		h.metrics.drop(DropHookQueueFull)
+		return false
+	}
+}
+
+func (h *hooks) run() {
+	defer close(h.stopped)
+
+	for {
+		select {
+		case e := <-h.queue:
+			for _, r := range h.hooks {
+				if e.entry.Level >= r.level {
+					r.hook.Fire(e.ctx, e.entry)
+				}
+			}
+			atomic.AddInt64(&h.pending, -1)
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// close stops the goroutine running the hooks, once the queued entries are
+// flushed, and closes the hooks implementing io.Closer. Entries logged from
+// then on are not given to the hooks.
+func (h *hooks) close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&h.closed, 0, 1) {
+		return nil
+	}
+	h.flush(ctx)
+	close(h.done)
+	select {
+	case <-h.stopped:
+	case <-ctx.Done():
+	}
+
+	var errs []error
+	for _, r := range h.hooks {
+		errs = append(errs, closeContext(ctx, r.hook))
+	}
+	return errors.Join(errs...)
+}
+
+// flush waits, until the context is done, for the queued entries to be
+// handled by the hooks, then flushes the hooks which buffer entries.
+func (h *hooks) flush(ctx context.Context) {
+	for atomic.LoadInt64(&h.pending) > 0 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	for _, r := range h.hooks {
+		flush(ctx, r.hook)
+	}
+}
+
+// closeContext closes a hook or a writer, until the context is done when it
+// supports CloseContext.
+func closeContext(ctx context.Context, v interface{}) error {
+	switch c := v.(type) {
+	case interface{ CloseContext(context.Context) error }:
+		return c.CloseContext(ctx)
+	case io.Closer:
+		return c.Close()
+	}
+	return nil
+}
+
+// flush flushes a hook or a writer which buffers entries, until the context
+// is done when it supports FlushContext.
+func flush(ctx context.Context, v interface{}) {
+	switch f := v.(type) {
+	case interface{ FlushContext(context.Context) error }:
+		f.FlushContext(ctx)
+	case interface{ Flush() error }:
+		f.Flush()
+	case interface{ Sync() error }:
+		f.Sync()
+	}
+}
+
+// Deadline returns no deadline.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil: the context is never cancelled.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil.
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value returns the value of the parent context.
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+func newHookLogger(next log.Logger, h *hooks) log.Logger {
+	if h == nil {
+		return next
+	}
+	return hookLogger{next: next, hooks: h}
+}
+
+func (l hookLogger) Log(kv ...interface{}) error {
+	ctx, kv := contextOf(kv)
+	if lvl := levelOf(kv); lvl != "" {
+		l.hooks.enqueue(ctx, Entry{
+			Time:   time.Now(),
+			Level:  ToLevel(lvl),
+			Fields: fields(kv),
+		})
+	}
+	return l.next.Log(kv...)
+}
+
+// fields returns the key/value pairs as a map.
+func fields(kv []interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		m[fmt.Sprint(kv[i])] = v
+	}
+	return m
+}
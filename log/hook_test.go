@@ -0,0 +1,272 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"arcadium.dev/core/log"
+)
+
+func TestHook(t *testing.T) {
+	t.Run("entries at or above level", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			entries []log.Entry
+		)
+		hook := log.HookFunc(func(_ context.Context, e log.Entry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			entries = append(entries, e)
+			return nil
+		})
+
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+			log.WithHook(hook, log.LevelError),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Info("msg", "ignored")
+		l.Named("sql").With("id", "0000-111").Error("msg", "boom")
+		l.Flush()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if b.Len() != 2 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		if len(entries) != 1 {
+			t.Fatalf("Unexpected entries: %+v", entries)
+		}
+		e := entries[0]
+		if e.Level != log.LevelError {
+			t.Errorf("Unexpected level: %d", e.Level)
+		}
+		if e.Time.IsZero() {
+			t.Error("Expected a time")
+		}
+		for k, v := range map[string]string{"level": "error", "logger": "sql", "id": "0000-111", "msg": "boom"} {
+			if s, ok := e.Fields[k].(interface{ String() string }); ok && s.String() == v {
+				continue
+			}
+			if e.Fields[k] != v {
+				t.Errorf("Unexpected field %s: %+v", k, e.Fields[k])
+			}
+		}
+	})
+
+	t.Run("detached context", func(t *testing.T) {
+		result := make(chan error, 1)
+		var id string
+		hook := log.HookFunc(func(ctx context.Context, _ log.Entry) error {
+			id = log.RequestIDFromContext(ctx)
+			result <- ctx.Err()
+			return nil
+		})
+
+		l, err := log.New(
+			log.WithFormat(log.FormatNop),
+			log.WithHook(hook, log.LevelError),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		ctx, cancel := context.WithCancel(log.NewContextWithRequestID(context.Background(), "0000-111"))
+		cancel()
+		l.ErrorContext(ctx, "msg", "boom")
+		l.Flush()
+
+		if err := <-result; err != nil {
+			t.Errorf("Unexpected context error: %s", err)
+		}
+		if id != "0000-111" {
+			t.Errorf("Unexpected request id: %s", id)
+		}
+	})
+
+	t.Run("full queue", func(t *testing.T) {
+		var (
+			block   = make(chan struct{})
+			mu      sync.Mutex
+			entries int
+		)
+		hook := log.HookFunc(func(context.Context, log.Entry) error {
+			<-block
+			mu.Lock()
+			defer mu.Unlock()
+			entries++
+			return nil
+		})
+
+		l, err := log.New(
+			log.WithFormat(log.FormatNop),
+			log.WithHook(hook, log.LevelInfo),
+			log.WithHookQueueSize(1),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		// The first entry is taken by the hook, the second fills the queue, the
+		// remaining entries are dropped without blocking the logger.
+		for i := 0; i < 10; i++ {
+			l.Info("count", i)
+		}
+		close(block)
+		l.Flush()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if entries < 1 || entries > 2 {
+			t.Errorf("Unexpected number of entries: %d", entries)
+		}
+	})
+}
+
+func TestLoggerClose(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		entries int
+	)
+	hook := log.HookFunc(func(context.Context, log.Entry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		entries++
+		return nil
+	})
+
+	before := runtime.NumGoroutine()
+	w := &closeBuffer{StringBuffer: log.NewStringBuffer()}
+	l, err := log.New(log.WithOutput(w), log.WithHook(hook, log.LevelInfo))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	l.Info("msg", "before close")
+	if err := l.Close(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	l.Info("msg", "after close")
+
+	mu.Lock()
+	if entries != 1 {
+		t.Errorf("Unexpected number of entries: %d", entries)
+	}
+	mu.Unlock()
+	if !w.closed {
+		t.Error("Expected the output to be closed")
+	}
+
+	// The goroutine running the hooks is stopped.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Unexpected goroutines: %d, expected %d", n, before)
+	}
+}
+
+type closeBuffer struct {
+	*log.StringBuffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestWebhookHook(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var (
+			mu     sync.Mutex
+			bodies []map[string]interface{}
+			auth   string
+		)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+			}
+			auth = r.Header.Get("Authorization")
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			bodies = append(bodies, body)
+		}))
+		defer srv.Close()
+
+		hook := log.NewWebhookHook(srv.URL, log.WithWebhookHeader("Authorization", "Bearer token"))
+		l, err := log.New(
+			log.WithFormat(log.FormatNop),
+			log.WithHook(hook, log.LevelWarn),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Info("msg", "ignored")
+		l.Warn("msg", "careful", "count", 3)
+		l.Flush()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(bodies) != 1 {
+			t.Fatalf("Unexpected bodies: %+v", bodies)
+		}
+		if bodies[0]["level"] != "warn" || bodies[0]["msg"] != "careful" || bodies[0]["count"] != float64(3) {
+			t.Errorf("Unexpected body: %+v", bodies[0])
+		}
+		if _, ok := bodies[0]["ts"]; !ok {
+			t.Errorf("Expected a timestamp: %+v", bodies[0])
+		}
+		if auth != "Bearer token" {
+			t.Errorf("Unexpected authorization: %s", auth)
+		}
+	})
+
+	t.Run("failure status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		hook := log.NewWebhookHook(srv.URL, log.WithWebhookClient(srv.Client()))
+		err := hook.Fire(context.Background(), log.Entry{Level: log.LevelError, Fields: map[string]interface{}{"msg": "boom"}})
+		if err == nil || !strings.Contains(err.Error(), "unexpected status 503") {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+type (
+	// LevelWriter is an io.Writer which is also given the level of each log
+	// entry written to it. When the output given to WithOutput implements
+	// LevelWriter, the logger will call WriteLevel instead of Write.
+	LevelWriter interface {
+		io.Writer
+
+		// WriteLevel writes a single encoded log entry with the given level. The
+		// level is the value of the go-kit level key, e.g. "info", or empty if
+		// the entry has no level.
+		WriteLevel(level string, p []byte) (int, error)
+	}
+
+	// levelLogger encodes each entry into a buffer and hands the result,
+	// along with the entry's level, to a LevelWriter.
+	levelLogger struct {
+		writer LevelWriter
+		encode func(io.Writer) log.Logger
+	}
+)
+
+func newLevelLogger(w LevelWriter, encode func(io.Writer) log.Logger) log.Logger {
+	return levelLogger{writer: w, encode: encode}
+}
+
+func (l levelLogger) Log(kv ...interface{}) error {
+	var buf bytes.Buffer
+	if err := l.encode(&buf).Log(kv...); err != nil {
+		return err
+	}
+	_, err := l.writer.WriteLevel(levelOf(kv), buf.Bytes())
+	return err
+}
+
+// levelOf returns the value of the level key in the given key/value pairs.
+func levelOf(kv []interface{}) string {
+	key, _ := level.Key().(string)
+	return valueOf(kv, key)
+}
+
+// valueOf returns the value of the given key in the key/value pairs as a
+// string, or an empty string if the key is not present.
+func valueOf(kv []interface{}, key string) string {
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok && k == key {
+			if v, ok := kv[i+1].(fmt.Stringer); ok {
+				return v.String()
+			}
+			return fmt.Sprint(kv[i+1])
+		}
+	}
+	return ""
+}
@@ -17,15 +17,40 @@ package log // import "arcadium.dev/core/log
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+
+	"arcadium.dev/core/errors"
 )
 
 var (
 	DefaultLogger Logger
+
+	// levelValues holds the value of the level key for each level. The go-kit
+	// level package only provides debug, info, warn and error values.
+	levelValues = [...]interface{}{
+		LevelTrace: levelValue("trace"),
+		LevelDebug: level.DebugValue(),
+		LevelInfo:  level.InfoValue(),
+		LevelWarn:  level.WarnValue(),
+		LevelError: level.ErrorValue(),
+		LevelFatal: levelValue("fatal"),
+		LevelPanic: levelValue("panic"),
+	}
+)
+
+const (
+	// nameKey is the key of the name of a named logger.
+	nameKey = "logger"
+
+	// flushTimeout limits the time Flush waits for the hooks and the output.
+	flushTimeout = 5 * time.Second
 )
 
 func init() {
@@ -37,33 +62,57 @@ type (
 	Logger struct {
 		level  Level
 		logger log.Logger
+		name   string
+		state  *state
 	}
 
 	// Level defines the logging levels available to the Logger, with a level of
-	// debug logging all message and error logging only error message.
+	// trace logging all message and panic logging only panic messages.
 	Level uint
 
 	// Format defines the output formats of the logger. Supported formats are
-	// FormatLogfmt (the default), FormatJSON, and FormatNop (no logging).
+	// FormatLogfmt (the default), FormatJSON, FormatOTLP, and FormatNop (no
+	// logging).
 	Format uint
+
+	// state is shared by a logger and all loggers derived from it.
+	state struct {
+		writer  io.Writer
+		exit    func(code int)
+		hooks   *hooks
+		metrics *metrics
+		dedup   *dedupLogger
+
+		mu     sync.RWMutex
+		levels map[string]Level
+	}
 )
 
 const (
-	// LevelDebug provides the most verbose logging, allowing logs for debug, info,
-	// warn, and error.
-	LevelDebug Level = iota
+	// LevelTrace provides the most verbose logging, allowing logs for all
+	// levels.
+	LevelTrace Level = iota
+
+	// LevelDebug allows logs for debug, info, warn, error, fatal and panic.
+	LevelDebug
 
-	// LevelInfo is the default log level. It allows logs for info, warn, and
-	// error.
+	// LevelInfo is the default log level. It allows logs for info, warn,
+	// error, fatal and panic.
 	LevelInfo
 
-	// LevelWarn is reserved for logging warnings and errors.
+	// LevelWarn allows logs for warn, error, fatal and panic.
 	LevelWarn
 
-	// LevelError provides the least verbose logging, allowing logs only for
-	// errors.
+	// LevelError allows logs for error, fatal and panic.
 	LevelError
 
+	// LevelFatal allows logs only for fatal and panic.
+	LevelFatal
+
+	// LevelPanic provides the least verbose logging, allowing logs only for
+	// panics.
+	LevelPanic
+
 	// LevelInvalid indicates and invalid log level.
 	LevelInvalid
 
@@ -76,6 +125,11 @@ const (
 	// FormatNop will suppress logging output entirely.
 	FormatNop
 
+	// FormatOTLP exports each log entry as an OpenTelemetry log record, using
+	// the exporter given to the WithOTLPExporter option. Nothing is written
+	// to the output.
+	FormatOTLP
+
 	// FormatInvalid indicates an invalid log format.
 	FormatInvalid
 )
@@ -87,6 +141,7 @@ func New(opts ...Option) (Logger, error) {
 		format:      FormatLogfmt,
 		writer:      os.Stdout,
 		timestamped: true,
+		exit:        os.Exit,
 	}
 	for _, opt := range opts {
 		opt.apply(&o)
@@ -100,17 +155,61 @@ func New(opts ...Option) (Logger, error) {
 	if o.writer == nil {
 		return Logger{}, ErrInvalidOutput
 	}
+	if o.format == FormatOTLP && o.exporter == nil {
+		return Logger{}, ErrMissingExporter
+	}
+	if o.exporter != nil {
+		o.hooks = append(o.hooks, registeredHook{hook: o.exporter, level: LevelTrace})
+	}
+	levels := make(map[string]Level, len(o.levels))
+	for name, lvl := range o.levels {
+		if lvl >= LevelInvalid {
+			return Logger{}, fmt.Errorf("%w: %s=%d", ErrInvalidLevel, name, lvl)
+		}
+		levels[name] = lvl
+	}
 
-	l := Logger{level: o.level}
+	m, err := newMetrics(o.registerer)
+	if err != nil {
+		return Logger{}, err
+	}
+	if m != nil {
+		if dc, ok := o.writer.(dropCounter); ok {
+			dc.countDrops(m.drops)
+		}
+		for _, r := range o.hooks {
+			if dc, ok := r.hook.(dropCounter); ok {
+				dc.countDrops(m.drops)
+			}
+		}
+	}
+
+	l := Logger{
+		level: o.level,
+		state: &state{
+			writer:  o.writer,
+			exit:    o.exit,
+			hooks:   newHooks(o.hooks, o.hookQueueSize, m),
+			metrics: m,
+			levels:  levels,
+		},
+	}
 
 	switch o.format {
 	case FormatJSON:
-		l.logger = log.NewJSONLogger(log.NewSyncWriter(o.writer))
+		l.logger = newErrorLogger(newFormatLogger(o.writer, log.NewJSONLogger), true)
 	case FormatLogfmt:
-		l.logger = log.NewLogfmtLogger(log.NewSyncWriter(o.writer))
-	case FormatNop:
+		l.logger = newErrorLogger(newFormatLogger(o.writer, log.NewLogfmtLogger), false)
+	case FormatNop, FormatOTLP:
 		l.logger = log.NewNopLogger()
 	}
+	l.logger = newHookLogger(l.logger, l.state.hooks)
+	l.logger = newMetricsLogger(l.logger, m)
+	if o.dedupWindow > 0 {
+		l.state.dedup = newDedupLogger(l.logger, o.dedupWindow, o.dedupKeys, m)
+		l.logger = l.state.dedup
+	}
+	l.logger = newContextLogger(l.logger, l.state.hooks)
 
 	if o.timestamped {
 		l.logger = log.With(l.logger, "ts", log.DefaultTimestampUTC)
@@ -122,12 +221,34 @@ func New(opts ...Option) (Logger, error) {
 	return l, nil
 }
 
+// levelValue is the value of the level key for the levels not provided by
+// go-kit.
+type levelValue string
+
+func (v levelValue) String() string { return string(v) }
+
+// newFormatLogger returns a logger which encodes entries to the given writer.
+// A LevelWriter will also be given the level of each entry.
+func newFormatLogger(w io.Writer, encode func(io.Writer) log.Logger) log.Logger {
+	if lw, ok := w.(LevelWriter); ok {
+		return newLevelLogger(lw, encode)
+	}
+	return encode(log.NewSyncWriter(w))
+}
+
+// Trace logs a trace level message.
+func (l Logger) Trace(kv ...interface{}) {
+	l.log(LevelTrace, kv)
+}
+
+// Trace logs a trace level message to the default logger.
+func Trace(kv ...interface{}) {
+	DefaultLogger.Trace(kv...)
+}
+
 // Debug logs a debug level message.
 func (l Logger) Debug(kv ...interface{}) {
-	if l.level > LevelDebug {
-		return
-	}
-	level.Debug(l.logger).Log(kv...)
+	l.log(LevelDebug, kv)
 }
 
 // Debug logs an debug level message to the default logger.
@@ -137,10 +258,7 @@ func Debug(kv ...interface{}) {
 
 // Info logs an info level message.
 func (l Logger) Info(kv ...interface{}) {
-	if l.level > LevelInfo {
-		return
-	}
-	level.Info(l.logger).Log(kv...)
+	l.log(LevelInfo, kv)
 }
 
 // Info logs an info level message to the default logger.
@@ -150,10 +268,7 @@ func Info(kv ...interface{}) {
 
 // Warn logs a warn level message.
 func (l Logger) Warn(kv ...interface{}) {
-	if l.level > LevelWarn {
-		return
-	}
-	level.Warn(l.logger).Log(kv...)
+	l.log(LevelWarn, kv)
 }
 
 // Warn logs a warn level message to the default logger.
@@ -163,7 +278,7 @@ func Warn(kv ...interface{}) {
 
 // Error logs an error level message.
 func (l Logger) Error(kv ...interface{}) {
-	level.Error(l.logger).Log(kv...)
+	l.log(LevelError, kv)
 }
 
 // Error logs an error level message to the default logger.
@@ -171,17 +286,211 @@ func Error(kv ...interface{}) {
 	DefaultLogger.Error(kv...)
 }
 
-// Level returns the log level.
+// Fatal logs a fatal level message, flushes the output, and then exits with a
+// status code of 1. The exit can be replaced with the WithExit option.
+func (l Logger) Fatal(kv ...interface{}) {
+	l.log(LevelFatal, kv)
+	l.Flush()
+
+	exit := os.Exit
+	if l.state != nil && l.state.exit != nil {
+		exit = l.state.exit
+	}
+	exit(1)
+}
+
+// Fatal logs a fatal level message to the default logger, and then exits.
+func Fatal(kv ...interface{}) {
+	DefaultLogger.Fatal(kv...)
+}
+
+// Panic logs a panic level message, and then panics with the message.
+func (l Logger) Panic(kv ...interface{}) {
+	l.log(LevelPanic, kv)
+	l.Flush()
+	panic(message(kv))
+}
+
+// Panic logs a panic level message to the default logger, and then panics.
+func Panic(kv ...interface{}) {
+	DefaultLogger.Panic(kv...)
+}
+
+// log logs the key/value pairs at the given level, if the level is enabled.
+func (l Logger) log(lvl Level, kv []interface{}) {
+	if !l.Enabled(lvl) {
+		return
+	}
+	prefix := []interface{}{level.Key(), levelValues[lvl]}
+	if l.name != "" {
+		prefix = append(prefix, nameKey, l.name)
+	}
+	log.WithPrefix(l.logger, prefix...).Log(kv...)
+}
+
+// Flush logs the summary of any suppressed duplicate entries, waits for the
+// entries queued for the hooks to be handled, and then flushes the output of
+// the logger, if the output supports it. Flush returns within 5s.
+func (l Logger) Flush() {
+	if l.state == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if l.state.dedup != nil {
+		l.state.dedup.flush()
+	}
+	if l.state.hooks != nil {
+		l.state.hooks.flush(ctx)
+	}
+	flush(ctx, l.state.writer)
+}
+
+// Close flushes the logger, stops the goroutine running the hooks, and closes
+// the hooks and the output implementing io.Closer, e.g. an OTLPExporter or a
+// SyslogWriter. The standard output and error are not closed. Close waits up
+// to 5s for the entries to be flushed. The logger, and the loggers derived
+// from it, must not be used once closed.
+func (l Logger) Close() error {
+	if l.state == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if l.state.dedup != nil {
+		l.state.dedup.flush()
+	}
+	var errs []error
+	if l.state.hooks != nil {
+		errs = append(errs, l.state.hooks.close(ctx))
+	}
+	flush(ctx, l.state.writer)
+	if l.state.writer != os.Stdout && l.state.writer != os.Stderr {
+		errs = append(errs, closeContext(ctx, l.state.writer))
+	}
+	return errors.Join(errs...)
+}
+
+// Level returns the log level. For a named logger, this is the level override
+// of the closest name, if any.
 func (l Logger) Level() Level {
+	if lvl, ok := l.state.lookup(l.name); ok {
+		return lvl
+	}
 	return l.level
 }
 
+// Enabled reports whether the logger will log messages at the given level.
+func (l Logger) Enabled(lvl Level) bool {
+	return lvl >= l.Level()
+}
+
 // With returns a new contextual logger with keyvals prepended to those
 // passed to calls to log.
 func (l Logger) With(kv ...interface{}) Logger {
 	return Logger{
 		level:  l.level,
 		logger: log.With(l.logger, kv...),
+		name:   l.name,
+		state:  l.state,
+	}
+}
+
+// Named returns a new logger for the named component. The name is added to
+// each message with the logger key. The names of nested loggers are joined
+// with a dot, e.g. "sql.pool".
+//
+// The level of a named logger can be overridden with the WithLevels option or
+// at runtime with SetLevel. The override for the full name applies, or failing
+// that, the override of the closest parent.
+func (l Logger) Named(name string) Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return Logger{
+		level:  l.level,
+		logger: l.logger,
+		name:   name,
+		state:  l.state,
+	}
+}
+
+// Named returns a new named logger derived from the default logger.
+func Named(name string) Logger {
+	return DefaultLogger.Named(name)
+}
+
+// Name returns the name of the logger.
+func (l Logger) Name() string {
+	return l.name
+}
+
+// SetLevel overrides the level of the named logger, and all loggers derived
+// from the logger given the name. The override applies to every logger sharing
+// the output of this logger.
+func (l Logger) SetLevel(name string, lvl Level) error {
+	if lvl >= LevelInvalid {
+		return fmt.Errorf("%w: %d", ErrInvalidLevel, lvl)
+	}
+	if l.state == nil {
+		return ErrInvalidOutput
+	}
+	l.state.mu.Lock()
+	defer l.state.mu.Unlock()
+
+	l.state.levels[name] = lvl
+	return nil
+}
+
+// ClearLevel removes the level override of the named logger.
+func (l Logger) ClearLevel(name string) {
+	if l.state == nil {
+		return
+	}
+	l.state.mu.Lock()
+	defer l.state.mu.Unlock()
+
+	delete(l.state.levels, name)
+}
+
+// Levels returns the level overrides by name.
+func (l Logger) Levels() map[string]Level {
+	levels := make(map[string]Level)
+	if l.state == nil {
+		return levels
+	}
+	l.state.mu.RLock()
+	defer l.state.mu.RUnlock()
+
+	for name, lvl := range l.state.levels {
+		levels[name] = lvl
+	}
+	return levels
+}
+
+// lookup returns the level override of the given name, or of its closest
+// parent.
+func (s *state) lookup(name string) (Level, bool) {
+	if s == nil || name == "" {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.levels) == 0 {
+		return 0, false
+	}
+	for {
+		if lvl, ok := s.levels[name]; ok {
+			return lvl, true
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
 	}
 }
 
@@ -191,18 +500,47 @@ func ToLevel(l string) Level {
 	switch strings.ToLower(l) {
 	case "info", "": // An unset level string defaults to LevelInfo.
 		level = LevelInfo
+	case "trace":
+		level = LevelTrace
 	case "debug":
 		level = LevelDebug
 	case "warn":
 		level = LevelWarn
 	case "error":
 		level = LevelError
+	case "fatal":
+		level = LevelFatal
+	case "panic":
+		level = LevelPanic
 	default:
 		level = LevelInvalid
 	}
 	return level
 }
 
+// ParseLevels parses a comma separated list of level overrides for named
+// loggers, e.g. "sql=debug,http=warn", for use with the WithLevels option.
+func ParseLevels(s string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLevel, entry)
+		}
+		lvl := ToLevel(value)
+		if lvl == LevelInvalid {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLevel, entry)
+		}
+		levels[name] = lvl
+	}
+	return levels, nil
+}
+
 // ToFormat translates the given format as a string to a Format.
 func ToFormat(f string) Format {
 	format := FormatInvalid
@@ -213,22 +551,37 @@ func ToFormat(f string) Format {
 		format = FormatJSON
 	case "nop":
 		format = FormatNop
+	case "otlp":
+		format = FormatOTLP
 	default:
 		format = FormatInvalid
 	}
 	return format
 }
 
+// message returns the msg value of the given key/value pairs, or all of the
+// key/value pairs when there is no msg.
+func message(kv []interface{}) string {
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok && k == "msg" {
+			return fmt.Sprint(kv[i+1])
+		}
+	}
+	return strings.TrimSuffix(fmt.Sprintln(kv...), "\n")
+}
+
 // NewContextWithLogger returns a new context with the given logger.
 func NewContextWithLogger(ctx context.Context, logger Logger) context.Context {
 	return context.WithValue(ctx, loggerContextKey, logger)
 }
 
-// LoggerFromContext returns the logger for the current request.
+// LoggerFromContext returns the logger for the current request. When the
+// context holds no logger, the fallback set with SetContextFallback is
+// returned, by default a logger with the FormatNop format.
 func LoggerFromContext(ctx context.Context) Logger {
 	logger, ok := ctx.Value(loggerContextKey).(Logger)
 	if !ok {
-		logger, _ = New(WithFormat(FormatNop))
+		logger = contextFallback()
 	}
 	return logger
 }
@@ -115,6 +115,71 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestTrace(t *testing.T) {
+	t.Run("Level set to trace", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithLevel(log.LevelTrace),
+			log.WithFormat(log.FormatJSON),
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		l.Trace("a", "b")
+
+		if b.Len() != 1 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := "{\"a\":\"b\",\"level\":\"trace\"}\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+	})
+
+	t.Run("Level set above trace", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithLevel(log.LevelDebug),
+			log.WithOutput(b),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		l.Trace("a", "b")
+
+		if b.Len() != 0 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+	})
+
+	t.Run("Test global trace", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		_, err := log.New(
+			log.WithLevel(log.LevelTrace),
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+			log.AsDefault(),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		log.Trace("a", "b")
+
+		if b.Len() != 1 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := "level=trace a=b\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+	})
+}
+
 func TestDebug(t *testing.T) {
 	t.Run("Level set to debug", func(t *testing.T) {
 		b := log.NewStringBuffer()
@@ -447,6 +512,88 @@ func TestError(t *testing.T) {
 	})
 }
 
+func TestFatal(t *testing.T) {
+	t.Run("Level set to fatal", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		code := 0
+		l, err := log.New(
+			log.WithLevel(log.LevelFatal),
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+			log.WithExit(func(c int) { code = c }),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		l.Error("a", "b")
+		l.Fatal("msg", "startup failed")
+
+		if b.Len() != 1 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := "level=fatal msg=\"startup failed\"\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+		if code != 1 {
+			t.Errorf("Unexpected exit code: %d", code)
+		}
+	})
+
+	t.Run("Level set above fatal", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		exited := false
+		l, err := log.New(
+			log.WithLevel(log.LevelPanic),
+			log.WithOutput(b),
+			log.WithExit(func(int) { exited = true }),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		l.With("id", "0000-111").Fatal("a", "b")
+
+		if b.Len() != 0 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		if !exited {
+			t.Error("Expected exit to be called")
+		}
+	})
+}
+
+func TestPanic(t *testing.T) {
+	b := log.NewStringBuffer()
+	l, err := log.New(
+		log.WithLevel(log.LevelPanic),
+		log.WithFormat(log.FormatJSON),
+		log.WithOutput(b),
+		log.WithoutTimestamp(),
+	)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	defer func() {
+		r := recover()
+		if r != "invariant violated" {
+			t.Errorf("Unexpected panic: %+v", r)
+		}
+		if b.Len() != 1 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := "{\"level\":\"panic\",\"msg\":\"invariant violated\"}\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+	}()
+
+	l.Error("a", "b")
+	l.Panic("msg", "invariant violated")
+}
+
 func TestLogging(t *testing.T) {
 	b := log.NewStringBuffer()
 	l, err := log.New(
@@ -508,6 +655,143 @@ func TestWith(t *testing.T) {
 	}
 }
 
+func TestNamed(t *testing.T) {
+	t.Run("Name added", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		sql := l.Named("sql")
+		sql.With("id", "0000-111").Info("msg", "connected")
+		sql.Named("pool").Info("msg", "resized")
+
+		if sql.Name() != "sql" {
+			t.Errorf("Unexpected name: %s", sql.Name())
+		}
+		if b.Len() != 2 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := "level=info logger=sql id=0000-111 msg=connected\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+		expected = "level=info logger=sql.pool msg=resized\n"
+		if b.Index(1) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(1))
+		}
+	})
+
+	t.Run("Level overrides", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithLevels(map[string]log.Level{"sql": log.LevelDebug, "http": log.LevelWarn}),
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		sql, pool, http := l.Named("sql"), l.Named("sql").Named("pool"), l.Named("http")
+		if sql.Level() != log.LevelDebug || pool.Level() != log.LevelDebug {
+			t.Errorf("Unexpected sql levels: %d, %d", sql.Level(), pool.Level())
+		}
+		if http.Level() != log.LevelWarn {
+			t.Errorf("Unexpected http level: %d", http.Level())
+		}
+
+		l.Debug("msg", "root")
+		pool.Debug("msg", "pool")
+		http.Info("msg", "http")
+
+		if b.Len() != 1 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := "level=debug logger=sql.pool msg=pool\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+	})
+
+	t.Run("Runtime overrides", func(t *testing.T) {
+		b := log.NewStringBuffer()
+		l, err := log.New(
+			log.WithOutput(b),
+			log.WithoutTimestamp(),
+		)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		sql := l.Named("sql")
+
+		sql.Debug("msg", "hidden")
+		if err := l.SetLevel("sql", log.LevelDebug); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		sql.Debug("msg", "shown")
+		if levels := l.Levels(); len(levels) != 1 || levels["sql"] != log.LevelDebug {
+			t.Errorf("Unexpected levels: %+v", levels)
+		}
+		l.ClearLevel("sql")
+		sql.Debug("msg", "hidden")
+
+		if b.Len() != 1 {
+			t.Errorf("Unexpected buffer length: %d", b.Len())
+		}
+		expected := "level=debug logger=sql msg=shown\n"
+		if b.Index(0) != expected {
+			t.Errorf("\nExpected %sActual:  %s", expected, b.Index(0))
+		}
+
+		if err := l.SetLevel("sql", log.LevelInvalid); !errors.Is(err, log.ErrInvalidLevel) {
+			t.Errorf("\nExpected: %s\nActual:   %s", log.ErrInvalidLevel, err)
+		}
+	})
+
+	t.Run("Invalid override", func(t *testing.T) {
+		_, err := log.New(log.WithLevels(map[string]log.Level{"sql": log.Level(42)}))
+		if !errors.Is(err, log.ErrInvalidLevel) {
+			t.Errorf("\nExpected: %s\nActual:   %s", log.ErrInvalidLevel, err)
+		}
+	})
+}
+
+func TestParseLevels(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		levels, err := log.ParseLevels(" sql=debug, http = WARN,,")
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if len(levels) != 2 || levels["sql"] != log.LevelDebug || levels["http"] != log.LevelWarn {
+			t.Errorf("Unexpected levels: %+v", levels)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		levels, err := log.ParseLevels("")
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if len(levels) != 0 {
+			t.Errorf("Unexpected levels: %+v", levels)
+		}
+	})
+
+	for _, s := range []string{"sql", "=debug", "sql=", "sql=verbose"} {
+		t.Run("invalid "+s, func(t *testing.T) {
+			_, err := log.ParseLevels(s)
+			if !errors.Is(err, log.ErrInvalidLevel) {
+				t.Errorf("\nExpected: %s\nActual:   %s", log.ErrInvalidLevel, err)
+			}
+		})
+	}
+}
+
 func TestToLevel(t *testing.T) {
 	levels := []struct {
 		s string
@@ -522,6 +806,12 @@ func TestToLevel(t *testing.T) {
 		{s: "WARN", l: log.LevelWarn},
 		{s: "error", l: log.LevelError},
 		{s: "ERROR", l: log.LevelError},
+		{s: "trace", l: log.LevelTrace},
+		{s: "TRACE", l: log.LevelTrace},
+		{s: "fatal", l: log.LevelFatal},
+		{s: "FATAL", l: log.LevelFatal},
+		{s: "panic", l: log.LevelPanic},
+		{s: "PANIC", l: log.LevelPanic},
 		{s: "invalid", l: log.LevelInvalid},
 	}
 	for _, l := range levels {
@@ -543,6 +833,8 @@ func TestToFormat(t *testing.T) {
 		{s: "LOGFMT", f: log.FormatLogfmt},
 		{s: "nop", f: log.FormatNop},
 		{s: "NOP", f: log.FormatNop},
+		{s: "otlp", f: log.FormatOTLP},
+		{s: "OTLP", f: log.FormatOTLP},
 		{s: "invalid", f: log.FormatInvalid},
 	}
 	for _, f := range formats {
@@ -0,0 +1,285 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logtest // import "arcadium.dev/core/log/logtest"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+
+	"arcadium.dev/core/log"
+)
+
+type (
+	// Recorder is an io.Writer which captures log entries written in either
+	// the JSON or logfmt format, and parses each entry into an Entry.
+	Recorder struct {
+		mu      sync.RWMutex
+		lines   []string
+		entries []Entry
+		notify  chan struct{}
+	}
+
+	// Entry is a parsed log entry, mapping each key to its value. Values of
+	// logfmt entries are strings, values of JSON entries are those decoded by
+	// encoding/json, with numbers decoded as json.Number.
+	Entry map[string]interface{}
+)
+
+// New returns a logger, logging all levels without timestamps, which writes to
+// the returned Recorder. The recorder is attached to tb, see Recorder.Attach.
+// Additional options are applied after the defaults.
+func New(tb testing.TB, opts ...log.Option) (log.Logger, *Recorder) {
+	tb.Helper()
+
+	r := NewRecorder()
+	r.Attach(tb)
+
+	opts = append([]log.Option{
+		log.WithLevel(log.LevelTrace),
+		log.WithoutTimestamp(),
+	}, opts...)
+	opts = append(opts, log.WithOutput(r))
+
+	l, err := log.New(opts...)
+	if err != nil {
+		tb.Fatalf("failed to create logger: %s", err)
+	}
+	return l, r
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{notify: make(chan struct{})}
+}
+
+// Write parses and records the log entries in p.
+func (r *Recorder) Write(p []byte) (int, error) {
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		e, err := parse(line)
+		if err != nil {
+			return 0, err
+		}
+		entries = append(entries, e)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines = append(r.lines, strings.TrimRight(string(p), "\n"))
+	r.entries = append(r.entries, entries...)
+
+	// Wake up any waiters.
+	close(r.notify)
+	r.notify = make(chan struct{})
+
+	return len(p), nil
+}
+
+// Attach attaches the recorder to tb, so that the captured log lines are
+// written to the test log when the test fails.
+func (r *Recorder) Attach(tb testing.TB) {
+	tb.Cleanup(func() {
+		if !tb.Failed() {
+			return
+		}
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		for _, line := range r.lines {
+			tb.Log(line)
+		}
+	})
+}
+
+// Len returns the number of captured entries.
+func (r *Recorder) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.entries)
+}
+
+// Entries returns the captured entries.
+func (r *Recorder) Entries() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]Entry, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// Reset discards the captured entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines, r.entries = nil, nil
+}
+
+// Find returns the first entry matching the given key/value pairs, see
+// Entry.Matches.
+func (r *Recorder) Find(kv ...string) (Entry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.entries {
+		if e.Matches(kv...) {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Contains reports whether an entry matching the given key/value pairs was
+// captured.
+func (r *Recorder) Contains(kv ...string) bool {
+	_, ok := r.Find(kv...)
+	return ok
+}
+
+// Wait waits, up to the given timeout, for an entry matching the given
+// key/value pairs to be captured. This is useful when the code under test logs
+// asynchronously.
+func (r *Recorder) Wait(timeout time.Duration, kv ...string) (Entry, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		r.mu.RLock()
+		notify := r.notify
+		r.mu.RUnlock()
+
+		if e, ok := r.Find(kv...); ok {
+			return e, true
+		}
+		select {
+		case <-notify:
+		case <-deadline.C:
+			return nil, false
+		}
+	}
+}
+
+// AssertContains fails the test when no entry matching the given key/value
+// pairs was captured, e.g.
+//
+//	r.AssertContains(t, "level", "error", "msg", "failed to connect")
+func (r *Recorder) AssertContains(tb testing.TB, kv ...string) Entry {
+	tb.Helper()
+
+	e, ok := r.Find(kv...)
+	if !ok {
+		tb.Errorf("no log entry with %s", pairs(kv))
+	}
+	return e
+}
+
+// AssertNotContains fails the test when an entry matching the given key/value
+// pairs was captured.
+func (r *Recorder) AssertNotContains(tb testing.TB, kv ...string) {
+	tb.Helper()
+
+	if e, ok := r.Find(kv...); ok {
+		tb.Errorf("unexpected log entry with %s: %v", pairs(kv), e)
+	}
+}
+
+// AssertWait fails the test when no entry matching the given key/value pairs
+// is captured within the timeout.
+func (r *Recorder) AssertWait(tb testing.TB, timeout time.Duration, kv ...string) Entry {
+	tb.Helper()
+
+	e, ok := r.Wait(timeout, kv...)
+	if !ok {
+		tb.Errorf("no log entry with %s after %s", pairs(kv), timeout)
+	}
+	return e
+}
+
+// Matches reports whether the entry holds each of the given key/value pairs.
+// Values are compared with their string representation.
+func (e Entry) Matches(kv ...string) bool {
+	for i := 0; i < len(kv); i += 2 {
+		v, ok := e[kv[i]]
+		if !ok {
+			return false
+		}
+		if i+1 < len(kv) && fmt.Sprint(v) != kv[i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the string representation of the value of the given key, or
+// an empty string if the key is not present.
+func (e Entry) String(key string) string {
+	v, ok := e[key]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// parse parses a single JSON or logfmt encoded log entry.
+func parse(line string) (Entry, error) {
+	e := make(Entry)
+
+	if strings.HasPrefix(line, "{") {
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&e); err != nil {
+			return nil, fmt.Errorf("failed to parse json log entry: %w", err)
+		}
+		return e, nil
+	}
+
+	dec := logfmt.NewDecoder(bytes.NewBufferString(line))
+	for dec.ScanRecord() {
+		for dec.ScanKeyval() {
+			e[string(dec.Key())] = string(dec.Value())
+		}
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse logfmt log entry: %w", err)
+	}
+	return e, nil
+}
+
+func pairs(kv []string) string {
+	var b strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(kv[i])
+		if i+1 < len(kv) {
+			b.WriteString("=" + kv[i+1])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logtest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"arcadium.dev/core/log"
+	"arcadium.dev/core/log/logtest"
+)
+
+func TestRecorderLogfmt(t *testing.T) {
+	l, r := logtest.New(t)
+
+	l.Debug("msg", "starting")
+	l.Error("msg", "failed to connect", "addr", "127.0.0.1:5432", "count", 3)
+
+	if r.Len() != 2 {
+		t.Errorf("Unexpected number of entries: %d", r.Len())
+	}
+	e := r.AssertContains(t, "level", "error", "msg", "failed to connect")
+	if e.String("addr") != "127.0.0.1:5432" || e.String("count") != "3" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	r.AssertNotContains(t, "level", "warn")
+
+	if !r.Contains("level", "debug") {
+		t.Error("Expected a debug entry")
+	}
+	if r.Contains("level", "error", "msg", "starting") {
+		t.Error("Unexpected entry")
+	}
+	if r.Contains("missing") {
+		t.Error("Unexpected entry")
+	}
+}
+
+func TestRecorderJSON(t *testing.T) {
+	l, r := logtest.New(t, log.WithFormat(log.FormatJSON), log.WithLevel(log.LevelInfo))
+
+	l.Debug("msg", "hidden")
+	l.Warn("msg", "slow query", "ms", 1234)
+
+	entries := r.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	if !entries[0].Matches("level", "warn", "msg", "slow query", "ms", "1234") {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+
+	r.Reset()
+	if r.Len() != 0 {
+		t.Errorf("Unexpected number of entries: %d", r.Len())
+	}
+}
+
+func TestRecorderWait(t *testing.T) {
+	l, r := logtest.New(t)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(10 * time.Millisecond)
+			l.Info("msg", "tick", "count", i)
+		}
+	}()
+
+	r.AssertWait(t, 5*time.Second, "msg", "tick", "count", "2")
+
+	if _, ok := r.Wait(10*time.Millisecond, "msg", "never"); ok {
+		t.Error("Unexpected entry")
+	}
+}
+
+func TestRecorderAttach(t *testing.T) {
+	for _, failed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("failed %t", failed), func(t *testing.T) {
+			tb := &mockTB{TB: t, failed: failed}
+
+			r := logtest.NewRecorder()
+			r.Attach(tb)
+			if _, err := r.Write([]byte("level=info msg=hello\n")); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			tb.cleanup()
+
+			if failed && (len(tb.logs) != 1 || tb.logs[0] != "level=info msg=hello") {
+				t.Errorf("Unexpected logs: %+v", tb.logs)
+			}
+			if !failed && len(tb.logs) != 0 {
+				t.Errorf("Unexpected logs: %+v", tb.logs)
+			}
+		})
+	}
+}
+
+func TestRecorderParseFailure(t *testing.T) {
+	r := logtest.NewRecorder()
+	if _, err := r.Write([]byte("{not json\n")); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+type (
+	mockTB struct {
+		testing.TB
+
+		failed  bool
+		logs    []string
+		cleanup func()
+	}
+)
+
+func (m *mockTB) Cleanup(f func()) { m.cleanup = f }
+func (m *mockTB) Failed() bool     { return m.failed }
+
+func (m *mockTB) Log(args ...interface{}) {
+	m.logs = append(m.logs, fmt.Sprint(args...))
+}
@@ -0,0 +1,134 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"arcadium.dev/core/internal/prom"
+)
+
+// Reasons given by the log_dropped_entries_total counter.
+const (
+	// DropHookQueueFull counts entries not given to the hooks because the hook
+	// queue was full.
+	DropHookQueueFull = "hook_queue_full"
+
+	// DropOTLPQueueFull counts entries not exported because the queue of the
+	// OTLPExporter was full.
+	DropOTLPQueueFull = "otlp_queue_full"
+
+	// DropOTLPExportFailed counts entries not exported because their export
+	// failed, once retried.
+	DropOTLPExportFailed = "otlp_export_failed"
+
+	// DropSyslogQueueFull counts entries not sent because the queue of the
+	// SyslogWriter was full.
+	DropSyslogQueueFull = "syslog_queue_full"
+
+	// DropSyslogSendFailed counts entries not sent because the syslog
+	// collector remained unavailable.
+	DropSyslogSendFailed = "syslog_send_failed"
+)
+
+type (
+	// metrics holds the counters of the log entries.
+	metrics struct {
+		entries *prometheus.CounterVec
+		dropped *prometheus.CounterVec
+	}
+
+	// dropCounter is implemented by the hooks and outputs which drop entries
+	// on their own, e.g. when their queue is full, so the logger counts the
+	// drops.
+	dropCounter interface {
+		countDrops(drops func(reason string, n int))
+	}
+
+	// metricsLogger is a go-kit logger which counts each entry before passing
+	// it to the next logger.
+	metricsLogger struct {
+		next    log.Logger
+		metrics *metrics
+	}
+)
+
+// newMetrics creates the log counters and registers them with the registerer.
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	if reg == nil {
+		return nil, nil
+	}
+	entries, err := registerCounterVec(reg, prometheus.CounterOpts{
+		Name: "log_entries_total",
+		Help: "Total number of log entries by level and logger name.",
+	}, []string{"level", "logger"})
+	if err != nil {
+		return nil, err
+	}
+	dropped, err := registerCounterVec(reg, prometheus.CounterOpts{
+		Name: "log_dropped_entries_total",
+		Help: "Total number of log entries dropped or suppressed, by reason.",
+	}, []string{"reason"})
+	if err != nil {
+		return nil, err
+	}
+	return &metrics{entries: entries, dropped: dropped}, nil
+}
+
+func registerCounterVec(reg prometheus.Registerer, opts prometheus.CounterOpts, labels []string) (*prometheus.CounterVec, error) {
+	c, err := prom.Register(reg, prometheus.NewCounterVec(opts, labels))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register %s: %w", opts.Name, err)
+	}
+	return c.(*prometheus.CounterVec), nil
+}
+
+// entry counts an entry logged with the given level and logger name.
+func (m *metrics) entry(level, name string) {
+	if m == nil {
+		return
+	}
+	m.entries.WithLabelValues(level, name).Inc()
+}
+
+// drop counts an entry dropped for the given reason.
+func (m *metrics) drop(reason string) {
+	m.drops(reason, 1)
+}
+
+// drops counts n entries dropped for the given reason.
+func (m *metrics) drops(reason string, n int) {
+	if m == nil {
+		return
+	}
+	m.dropped.WithLabelValues(reason).Add(float64(n))
+}
+
+func newMetricsLogger(next log.Logger, m *metrics) log.Logger {
+	if m == nil {
+		return next
+	}
+	return metricsLogger{next: next, metrics: m}
+}
+
+func (l metricsLogger) Log(kv ...interface{}) error {
+	if lvl := levelOf(kv); lvl != "" {
+		l.metrics.entry(lvl, valueOf(kv, nameKey))
+	}
+	return l.next.Log(kv...)
+}
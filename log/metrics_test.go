@@ -0,0 +1,219 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"arcadium.dev/core/log"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("entries by level and name", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		l, err := log.New(
+			log.WithLevel(log.LevelInfo),
+			log.WithFormat(log.FormatNop),
+			log.WithMetrics(reg),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Debug("msg", "filtered")
+		l.Info("msg", "one")
+		l.Error("msg", "two")
+		l.Error("msg", "three")
+		l.Named("sql").Warn("msg", "four")
+
+		// A second logger on the same registry shares the counters.
+		l2, err := log.New(log.WithFormat(log.FormatNop), log.WithMetrics(reg))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		l2.Info("msg", "five")
+
+		expected := `
+# HELP log_entries_total Total number of log entries by level and logger name.
+# TYPE log_entries_total counter
+log_entries_total{level="error",logger=""} 2
+log_entries_total{level="info",logger=""} 2
+log_entries_total{level="warn",logger="sql"} 1
+`
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "log_entries_total"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("dropped entries", func(t *testing.T) {
+		block := make(chan struct{})
+		hook := log.HookFunc(func(context.Context, log.Entry) error {
+			<-block
+			return nil
+		})
+
+		reg := prometheus.NewRegistry()
+		l, err := log.New(
+			log.WithFormat(log.FormatNop),
+			log.WithMetrics(reg),
+			log.WithHook(hook, log.LevelInfo),
+			log.WithHookQueueSize(1),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		for i := 0; i < 10; i++ {
+			l.Info("count", i)
+		}
+		close(block)
+		l.Flush()
+
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		found := false
+		for _, mf := range mfs {
+			if mf.GetName() != "log_dropped_entries_total" {
+				continue
+			}
+			found = true
+			if len(mf.GetMetric()) != 1 {
+				t.Fatalf("Unexpected metrics: %+v", mf.GetMetric())
+			}
+			// At most two entries are taken by the hook and the queue.
+			m := mf.GetMetric()[0]
+			if m.GetLabel()[0].GetValue() != log.DropHookQueueFull || m.GetCounter().GetValue() < 8 {
+				t.Errorf("Unexpected metric: %+v", m)
+			}
+		}
+		if !found {
+			t.Error("Expected log_dropped_entries_total")
+		}
+	})
+
+	t.Run("otlp drops", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		e := log.NewOTLPExporter(srv.URL, log.WithOTLPBatch(1, time.Hour), log.WithOTLPQueueSize(1))
+		reg := prometheus.NewRegistry()
+		l, err := log.New(log.WithFormat(log.FormatNop), log.WithMetrics(reg), log.WithOTLPExporter(e))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		// The first record is being exported, the second fills the queue, the
+		// remaining records are dropped.
+		for i := 0; i < 5; i++ {
+			l.Info("count", i)
+		}
+		l.Flush()
+		close(release)
+		e.Close()
+
+		if n := droppedEntries(t, reg, log.DropOTLPQueueFull); n < 3 {
+			t.Errorf("Unexpected %s drops: %f", log.DropOTLPQueueFull, n)
+		}
+		if n := droppedEntries(t, reg, log.DropOTLPExportFailed); n < 1 {
+			t.Errorf("Unexpected %s drops: %f", log.DropOTLPExportFailed, n)
+		}
+	})
+
+	t.Run("syslog drops", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %s", err)
+		}
+		defer listener.Close()
+		go func() {
+			if conn, err := listener.Accept(); err == nil {
+				conn.Close()
+			}
+		}()
+
+		w, err := log.NewSyslogWriter("tcp", listener.Addr().String(), log.WithSyslogRetry(1, time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer w.Close()
+		listener.Close()
+
+		reg := prometheus.NewRegistry()
+		l, err := log.New(log.WithOutput(w), log.WithMetrics(reg))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		// With the collector gone, the entries are dropped once the writer
+		// notices the dropped connection.
+		deadline := time.Now().Add(5 * time.Second)
+		for droppedEntries(t, reg, log.DropSyslogSendFailed) == 0 && time.Now().Before(deadline) {
+			l.Info("msg", "lost")
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := droppedEntries(t, reg, log.DropSyslogSendFailed); n == 0 {
+			t.Errorf("Expected %s drops", log.DropSyslogSendFailed)
+		}
+	})
+
+	t.Run("registration failure", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "log_entries_total",
+			Help: "Conflicting collector.",
+		}))
+
+		_, err := log.New(log.WithMetrics(reg))
+		if err == nil || !strings.Contains(err.Error(), "failed to register log_entries_total") {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	})
+}
+
+// droppedEntries returns the value of log_dropped_entries_total for the reason.
+func droppedEntries(t *testing.T, reg *prometheus.Registry, reason string) float64 {
+	t.Helper()
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "log_dropped_entries_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == reason {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -14,7 +14,12 @@
 
 package log // import "arcadium.dev/core/log
 
-import "io"
+import (
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 type (
 	// Option provides for Logger configuration.
@@ -30,6 +35,13 @@ func WithLevel(level Level) Option {
 	})
 }
 
+// WithLevels overrides the level of named loggers, see Logger.Named.
+func WithLevels(levels map[string]Level) Option {
+	return newOption(func(opts *options) {
+		opts.levels = levels
+	})
+}
+
 // WithFormat allows the format to be configured. The default format is
 // FormatLogfmt.
 func WithFormat(format Format) Option {
@@ -54,6 +66,62 @@ func WithoutTimestamp() Option {
 	})
 }
 
+// WithExit replaces os.Exit as the function called by Fatal after logging.
+func WithExit(exit func(code int)) Option {
+	return newOption(func(opts *options) {
+		opts.exit = exit
+	})
+}
+
+// WithHook registers a hook to be invoked for entries at or above the given
+// level. This option may be given more than once. The hooks are run by a
+// goroutine which is stopped by Close.
+func WithHook(hook Hook, level Level) Option {
+	return newOption(func(opts *options) {
+		opts.hooks = append(opts.hooks, registeredHook{hook: hook, level: level})
+	})
+}
+
+// WithHookQueueSize sets the number of entries which may be queued for the
+// hooks, the default is 1024. Entries logged while the queue is full are not
+// given to the hooks.
+func WithHookQueueSize(size int) Option {
+	return newOption(func(opts *options) {
+		opts.hookQueueSize = size
+	})
+}
+
+// WithMetrics counts the log entries by level and logger name, and the entries
+// dropped, in counters registered with the given registerer. Loggers given
+// the same registerer share the counters.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return newOption(func(opts *options) {
+		opts.registerer = reg
+	})
+}
+
+// WithDeduplication collapses identical entries logged within the window into
+// a single entry. Entries are identical when they have the same level, logger
+// name, msg, and values of the given keys. The first entry is logged
+// immediately, and when the window closes, or a different entry is logged, the
+// last duplicate is logged with a repeated key holding the number of
+// duplicates.
+func WithDeduplication(window time.Duration, keys ...string) Option {
+	return newOption(func(opts *options) {
+		opts.dedupWindow = window
+		opts.dedupKeys = keys
+	})
+}
+
+// WithOTLPExporter exports the log entries with the given exporter. With the
+// FormatOTLP format the exporter is the only output of the logger, otherwise
+// entries are exported in addition to being written to the output.
+func WithOTLPExporter(exporter *OTLPExporter) Option {
+	return newOption(func(opts *options) {
+		opts.exporter = exporter
+	})
+}
+
 // As default sets the DefaultLogger.
 func AsDefault() Option {
 	return newOption(func(opts *options) {
@@ -64,10 +132,20 @@ func AsDefault() Option {
 type (
 	options struct {
 		level       Level
+		levels      map[string]Level
 		format      Format
 		writer      io.Writer
 		timestamped bool
 		asDefault   bool
+		exit        func(code int)
+
+		hooks         []registeredHook
+		hookQueueSize int
+		registerer    prometheus.Registerer
+		exporter      *OTLPExporter
+
+		dedupWindow time.Duration
+		dedupKeys   []string
 	}
 
 	option struct {
@@ -79,6 +157,9 @@ type (
 
 const (
 	loggerContextKey = contextKey(iota + 1)
+	spanContextKey
+	requestIDContextKey
+	userIDContextKey
 )
 
 var (
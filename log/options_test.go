@@ -15,7 +15,11 @@
 package log
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestWithLevel(t *testing.T) {
@@ -49,6 +53,19 @@ func TestWithLevel(t *testing.T) {
 	})
 }
 
+func TestWithLevels(t *testing.T) {
+	var opts options
+
+	o := WithLevels(map[string]Level{"sql": LevelDebug})
+	if o == nil {
+		t.Fatal("option expected")
+	}
+	o.apply(&opts)
+	if len(opts.levels) != 1 || opts.levels["sql"] != LevelDebug {
+		t.Errorf("Unexpected levels: %+v", opts.levels)
+	}
+}
+
 func TestWithFormat(t *testing.T) {
 	formatCheck := func(t *testing.T, f Format) {
 		t.Helper()
@@ -104,6 +121,81 @@ func TestWithoutTimestamp(t *testing.T) {
 	}
 }
 
+func TestWithExit(t *testing.T) {
+	var (
+		opts options
+		code int
+	)
+
+	o := WithExit(func(c int) { code = c })
+	if o == nil {
+		t.Fatal("option expected")
+	}
+	o.apply(&opts)
+	if opts.exit == nil {
+		t.Fatal("Expected exit to be set")
+	}
+	opts.exit(42)
+	if code != 42 {
+		t.Errorf("Unexpected exit code: %d", code)
+	}
+}
+
+func TestWithHook(t *testing.T) {
+	var opts options
+
+	hook := HookFunc(func(context.Context, Entry) error { return nil })
+	o := WithHook(hook, LevelError)
+	if o == nil {
+		t.Fatal("option expected")
+	}
+	o.apply(&opts)
+	WithHook(hook, LevelWarn).apply(&opts)
+	if len(opts.hooks) != 2 || opts.hooks[0].level != LevelError || opts.hooks[1].level != LevelWarn {
+		t.Errorf("Unexpected hooks: %+v", opts.hooks)
+	}
+}
+
+func TestWithHookQueueSize(t *testing.T) {
+	var opts options
+
+	o := WithHookQueueSize(42)
+	if o == nil {
+		t.Fatal("option expected")
+	}
+	o.apply(&opts)
+	if opts.hookQueueSize != 42 {
+		t.Errorf("Unexpected hook queue size: %d", opts.hookQueueSize)
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	var opts options
+
+	reg := prometheus.NewRegistry()
+	o := WithMetrics(reg)
+	if o == nil {
+		t.Fatal("option expected")
+	}
+	o.apply(&opts)
+	if opts.registerer != reg {
+		t.Errorf("Unexpected registerer: %+v", opts.registerer)
+	}
+}
+
+func TestWithDeduplication(t *testing.T) {
+	var opts options
+
+	o := WithDeduplication(time.Second, "addr")
+	if o == nil {
+		t.Fatal("option expected")
+	}
+	o.apply(&opts)
+	if opts.dedupWindow != time.Second || len(opts.dedupKeys) != 1 || opts.dedupKeys[0] != "addr" {
+		t.Errorf("Unexpected deduplication: %s %+v", opts.dedupWindow, opts.dedupKeys)
+	}
+}
+
 func TestAsDefault(t *testing.T) {
 	var opts options
 
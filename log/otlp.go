@@ -0,0 +1,589 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOTLPBatchSize = 512
+	defaultOTLPQueueSize = 2048
+	defaultOTLPInterval  = 5 * time.Second
+	defaultOTLPTimeout   = 10 * time.Second
+	defaultOTLPRetries   = 3
+	defaultOTLPBackoff   = time.Second
+
+	// otlpScope is the instrumentation scope of the exported records.
+	otlpScope = "arcadium.dev/core/log"
+
+	// traceIDKey and spanIDKey are the keys of the trace and span IDs, as hex
+	// strings, in an entry.
+	traceIDKey = "trace_id"
+	spanIDKey  = "span_id"
+)
+
+var (
+	// otlpSeverities maps the levels to the OTLP severity numbers.
+	otlpSeverities = [...]int{
+		LevelTrace: 1,
+		LevelDebug: 5,
+		LevelInfo:  9,
+		LevelWarn:  13,
+		LevelError: 17,
+		LevelFatal: 21,
+		LevelPanic: 22,
+	}
+
+	// otlpReserved are the keys of an entry which are not exported as
+	// attributes of the record.
+	otlpReserved = map[string]bool{
+		"ts":       true,
+		"level":    true,
+		"msg":      true,
+		traceIDKey: true,
+		spanIDKey:  true,
+	}
+)
+
+type (
+	// SpanContext identifies the span of a trace an entry was logged in.
+	SpanContext struct {
+		TraceID [16]byte
+		SpanID  [8]byte
+		Flags   byte
+	}
+
+	// OTLPExporter is a Hook which converts entries to OpenTelemetry log
+	// records, and exports them in batches to a collector using OTLP/HTTP with
+	// JSON encoding. A batch is exported when it is full, or when the export
+	// interval elapses. Failed exports are retried with an exponential
+	// backoff. A single export runs at a time.
+	//
+	// The exporter is used as the output of a logger with the FormatOTLP
+	// format, or alongside another format, see WithOTLPExporter.
+	OTLPExporter struct {
+		url       string
+		client    *http.Client
+		headers   http.Header
+		resource  []otlpKeyValue
+		span      func(context.Context) (SpanContext, bool)
+		batchSize int
+		queueSize int
+		interval  time.Duration
+		retries   int
+		backoff   time.Duration
+
+		mu      sync.Mutex
+		records []otlpLogRecord
+		closed  bool
+
+		dropMu sync.Mutex
+		onDrop func(reason string, n int)
+
+		exporting chan struct{}
+		ready     chan struct{}
+		done      chan struct{}
+		wg        sync.WaitGroup
+	}
+
+	// OTLPOption provides for OTLPExporter configuration.
+	OTLPOption interface {
+		apply(*OTLPExporter)
+	}
+)
+
+var _ Hook = (*OTLPExporter)(nil)
+
+// NewOTLPExporter returns an exporter which posts log records to the given
+// OTLP/HTTP logs endpoint, e.g. http://localhost:4318/v1/logs.
+func NewOTLPExporter(url string, opts ...OTLPOption) *OTLPExporter {
+	e := &OTLPExporter{
+		url:       url,
+		client:    &http.Client{Timeout: defaultOTLPTimeout},
+		headers:   make(http.Header),
+		span:      SpanFromContext,
+		batchSize: defaultOTLPBatchSize,
+		queueSize: defaultOTLPQueueSize,
+		interval:  defaultOTLPInterval,
+		retries:   defaultOTLPRetries,
+		backoff:   defaultOTLPBackoff,
+		exporting: make(chan struct{}, 1),
+		ready:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(e)
+	}
+	if e.queueSize < e.batchSize {
+		e.queueSize = e.batchSize
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// Fire converts the entry to a log record and queues it for export. The trace
+// and span IDs are taken from the context, or else from the trace_id and
+// span_id fields of the entry. An error is returned if the queue is full or
+// the exporter is closed.
+func (e *OTLPExporter) Fire(ctx context.Context, entry Entry) error {
+	r := e.record(ctx, entry)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return errors.New("failed to export entry: exporter closed")
+	}
+	if len(e.records) >= e.queueSize {
+		e.drop(DropOTLPQueueFull, 1)
+		return errors.New("failed to export entry: queue full")
+	}
+	e.records = append(e.records, r)
+	if len(e.records) >= e.batchSize {
+		select {
+		case e.ready <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush exports the queued records.
+func (e *OTLPExporter) Flush() error {
+	return e.FlushContext(context.Background())
+}
+
+// FlushContext exports the queued records, until the context is done. The
+// export in progress when the context is done is abandoned, and the records
+// not yet exported remain queued.
+func (e *OTLPExporter) FlushContext(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to export log records: %w", err)
+		}
+		batch := e.batch()
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.export(ctx, batch); err != nil {
+			e.drop(DropOTLPExportFailed, len(batch))
+			return err
+		}
+	}
+}
+
+// countDrops implements dropCounter.
+func (e *OTLPExporter) countDrops(drops func(reason string, n int)) {
+	e.dropMu.Lock()
+	defer e.dropMu.Unlock()
+
+	e.onDrop = drops
+}
+
+// drop reports n records dropped for the given reason to the logger.
+func (e *OTLPExporter) drop(reason string, n int) {
+	e.dropMu.Lock()
+	drops := e.onDrop
+	e.dropMu.Unlock()
+
+	if drops != nil {
+		drops(reason, n)
+	}
+}
+
+// Close exports the queued records and stops the exporter.
+func (e *OTLPExporter) Close() error {
+	return e.CloseContext(context.Background())
+}
+
+// CloseContext exports the queued records, until the context is done, and
+// stops the exporter.
+func (e *OTLPExporter) CloseContext(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.done)
+
+	// Wait for the export in progress, if any.
+	stopped := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to export log records: %w", ctx.Err())
+	}
+	return e.FlushContext(ctx)
+}
+
+func (e *OTLPExporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.ready:
+		}
+		// Errors are discarded, as there is nobody to return them to.
+		e.Flush()
+	}
+}
+
+// batch removes and returns up to a batch of the queued records.
+func (e *OTLPExporter) batch() []otlpLogRecord {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n := len(e.records)
+	if n > e.batchSize {
+		n = e.batchSize
+	}
+	batch := make([]otlpLogRecord, n)
+	copy(batch, e.records)
+	e.records = e.records[:copy(e.records, e.records[n:])]
+	return batch
+}
+
+// export posts the batch to the collector, retrying failures which may be
+// transient, until the context is done.
+func (e *OTLPExporter) export(ctx context.Context, batch []otlpLogRecord) error {
+	select {
+	case e.exporting <- struct{}{}:
+		defer func() { <-e.exporting }()
+	case <-ctx.Done():
+		return fmt.Errorf("failed to export log records: %w", ctx.Err())
+	}
+
+	body, err := json.Marshal(otlpRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{Attributes: e.resource},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpInstrumentationScope{Name: otlpScope},
+				LogRecords: batch,
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode log records: %w", err)
+	}
+
+	backoff := e.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := e.post(ctx, body)
+		if err == nil || !retry || attempt >= e.retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a single export request. It reports whether a failure may be
+// retried.
+func (e *OTLPExporter) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create export request: %w", err)
+	}
+	for k, v := range e.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to export log records: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable,
+		resp.StatusCode == http.StatusGatewayTimeout:
+		return true, fmt.Errorf("failed to export log records: unexpected status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("failed to export log records: unexpected status %d", resp.StatusCode)
+	}
+}
+
+// record converts the entry to an OTLP log record.
+func (e *OTLPExporter) record(ctx context.Context, entry Entry) otlpLogRecord {
+	r := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(entry.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+	if entry.Level < LevelInvalid {
+		r.SeverityNumber = otlpSeverities[entry.Level]
+		r.SeverityText = fmt.Sprint(levelValues[entry.Level])
+	}
+	if msg, ok := entry.Fields["msg"]; ok {
+		v := otlpValue(msg)
+		r.Body = &v
+	}
+
+	if sc, ok := e.span(ctx); ok {
+		r.TraceID = hex.EncodeToString(sc.TraceID[:])
+		r.SpanID = hex.EncodeToString(sc.SpanID[:])
+		r.Flags = int(sc.Flags)
+	} else {
+		r.TraceID = hexField(entry.Fields, traceIDKey, 16)
+		r.SpanID = hexField(entry.Fields, spanIDKey, 8)
+	}
+
+	keys := make([]string, 0, len(entry.Fields))
+	for k := range entry.Fields {
+		if !otlpReserved[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.Attributes = append(r.Attributes, otlpKeyValue{Key: k, Value: otlpValue(entry.Fields[k])})
+	}
+	return r
+}
+
+// hexField returns the field with the given key if it is a hex string of n
+// bytes, otherwise an empty string.
+func hexField(fields map[string]interface{}, key string, n int) string {
+	s, ok := fields[key].(string)
+	if !ok || len(s) != 2*n {
+		return ""
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return ""
+	}
+	return s
+}
+
+// otlpValue converts a field value to an OTLP any value.
+func otlpValue(v interface{}) otlpAnyValue {
+	switch v := v.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case int:
+		return otlpInt(int64(v))
+	case int8:
+		return otlpInt(int64(v))
+	case int16:
+		return otlpInt(int64(v))
+	case int32:
+		return otlpInt(int64(v))
+	case int64:
+		return otlpInt(v)
+	case uint8:
+		return otlpInt(int64(v))
+	case uint16:
+		return otlpInt(int64(v))
+	case uint32:
+		return otlpInt(int64(v))
+	case float32:
+		f := float64(v)
+		return otlpAnyValue{DoubleValue: &f}
+	case float64:
+		return otlpAnyValue{DoubleValue: &v}
+	case error:
+		s := v.Error()
+		return otlpAnyValue{StringValue: &s}
+	case fmt.Stringer:
+		s := v.String()
+		return otlpAnyValue{StringValue: &s}
+	default:
+		s := fmt.Sprint(v)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+func otlpInt(i int64) otlpAnyValue {
+	s := strconv.FormatInt(i, 10)
+	return otlpAnyValue{IntValue: &s}
+}
+
+// WithOTLPClient sets the http client used to export records.
+func WithOTLPClient(client *http.Client) OTLPOption {
+	return newOTLPOption(func(e *OTLPExporter) {
+		e.client = client
+	})
+}
+
+// WithOTLPHeader adds a header, e.g. an authorization token, to each export
+// request.
+func WithOTLPHeader(key, value string) OTLPOption {
+	return newOTLPOption(func(e *OTLPExporter) {
+		e.headers.Add(key, value)
+	})
+}
+
+// WithOTLPResource adds an attribute, e.g. service.name, to the resource
+// describing the source of the records.
+func WithOTLPResource(key, value string) OTLPOption {
+	return newOTLPOption(func(e *OTLPExporter) {
+		e.resource = append(e.resource, otlpKeyValue{Key: key, Value: otlpValue(value)})
+	})
+}
+
+// WithOTLPBatch sets the maximum number of records in an export request, the
+// default is 512, and the interval after which queued records are exported,
+// the default is 5s.
+func WithOTLPBatch(size int, interval time.Duration) OTLPOption {
+	return newOTLPOption(func(e *OTLPExporter) {
+		if size > 0 {
+			e.batchSize = size
+		}
+		if interval > 0 {
+			e.interval = interval
+		}
+	})
+}
+
+// WithOTLPQueueSize sets the maximum number of records queued for export, the
+// default is 2048. Records are dropped while the queue is full.
+func WithOTLPQueueSize(size int) OTLPOption {
+	return newOTLPOption(func(e *OTLPExporter) {
+		e.queueSize = size
+	})
+}
+
+// WithOTLPRetry sets the number of times a failed export is retried, the
+// default is 3, and the backoff before the first retry, the default is 1s. The
+// backoff doubles with each retry.
+func WithOTLPRetry(retries int, backoff time.Duration) OTLPOption {
+	return newOTLPOption(func(e *OTLPExporter) {
+		e.retries = retries
+		e.backoff = backoff
+	})
+}
+
+// WithOTLPSpanContext sets the function used to take the span context from the
+// context given to Fire, e.g. to adapt a tracing library. The default is
+// SpanFromContext.
+func WithOTLPSpanContext(f func(context.Context) (SpanContext, bool)) OTLPOption {
+	return newOTLPOption(func(e *OTLPExporter) {
+		e.span = f
+	})
+}
+
+// NewContextWithSpan returns a new context with the given span context.
+func NewContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+// SpanFromContext returns the span context held by the context, if any.
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	return sc, ok
+}
+
+type (
+	otlpOption struct {
+		f func(*OTLPExporter)
+	}
+
+	// The following types are the JSON encoding of an OTLP
+	// ExportLogsServiceRequest.
+
+	otlpRequest struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+
+	otlpScopeLogs struct {
+		Scope      otlpInstrumentationScope `json:"scope"`
+		LogRecords []otlpLogRecord          `json:"logRecords"`
+	}
+
+	otlpInstrumentationScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpLogRecord struct {
+		TimeUnixNano         string         `json:"timeUnixNano"`
+		ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+		SeverityNumber       int            `json:"severityNumber,omitempty"`
+		SeverityText         string         `json:"severityText,omitempty"`
+		Body                 *otlpAnyValue  `json:"body,omitempty"`
+		Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+		Flags                int            `json:"flags,omitempty"`
+		TraceID              string         `json:"traceId,omitempty"`
+		SpanID               string         `json:"spanId,omitempty"`
+	}
+
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+func newOTLPOption(f func(*OTLPExporter)) otlpOption {
+	return otlpOption{f: f}
+}
+
+func (o otlpOption) apply(e *OTLPExporter) {
+	o.f(e)
+}
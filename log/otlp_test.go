@@ -0,0 +1,291 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"arcadium.dev/core/log"
+)
+
+type (
+	// collector is a stand-in for an OpenTelemetry collector.
+	collector struct {
+		mu       sync.Mutex
+		requests []otlpRequest
+		headers  []http.Header
+		statuses []int
+	}
+
+	otlpRequest struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []otlpKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []otlpRecord `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+
+	otlpRecord struct {
+		TimeUnixNano   string         `json:"timeUnixNano"`
+		SeverityNumber int            `json:"severityNumber"`
+		SeverityText   string         `json:"severityText"`
+		Body           otlpValue      `json:"body"`
+		Attributes     []otlpKeyValue `json:"attributes"`
+		TraceID        string         `json:"traceId"`
+		SpanID         string         `json:"spanId"`
+		Flags          int            `json:"flags"`
+	}
+
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue *string  `json:"stringValue"`
+		IntValue    *string  `json:"intValue"`
+		BoolValue   *bool    `json:"boolValue"`
+		DoubleValue *float64 `json:"doubleValue"`
+	}
+)
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.statuses) > 0 {
+		status := c.statuses[0]
+		c.statuses = c.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header)
+}
+
+func (c *collector) records() []otlpRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var records []otlpRecord
+	for _, req := range c.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+func TestOTLPExporter(t *testing.T) {
+	t.Run("format otlp", func(t *testing.T) {
+		c := &collector{}
+		srv := httptest.NewServer(c)
+		defer srv.Close()
+
+		e := log.NewOTLPExporter(srv.URL,
+			log.WithOTLPHeader("Authorization", "Bearer token"),
+			log.WithOTLPResource("service.name", "arcadium"),
+		)
+		defer e.Close()
+
+		b := log.NewStringBuffer()
+		l, err := log.New(log.WithFormat(log.FormatOTLP), log.WithOTLPExporter(e), log.WithOutput(b))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		l.Named("sql").Warn("msg", "slow query", "ms", 1234, "ok", false,
+			"trace_id", "0102030405060708090a0b0c0d0e0f10", "span_id", "0102030405060708")
+		l.Flush()
+
+		if b.Len() != 0 {
+			t.Errorf("Unexpected output: %s", b.Index(0))
+		}
+		records := c.records()
+		if len(records) != 1 {
+			t.Fatalf("Unexpected records: %+v", records)
+		}
+		r := records[0]
+		if r.SeverityNumber != 13 || r.SeverityText != "warn" || *r.Body.StringValue != "slow query" ||
+			r.TraceID != "0102030405060708090a0b0c0d0e0f10" || r.SpanID != "0102030405060708" || r.TimeUnixNano == "" {
+			t.Errorf("Unexpected record: %+v", r)
+		}
+		if len(r.Attributes) != 3 ||
+			r.Attributes[0].Key != "logger" || *r.Attributes[0].Value.StringValue != "sql" ||
+			r.Attributes[1].Key != "ms" || *r.Attributes[1].Value.IntValue != "1234" ||
+			r.Attributes[2].Key != "ok" || *r.Attributes[2].Value.BoolValue {
+			t.Errorf("Unexpected attributes: %+v", r.Attributes)
+		}
+		if c.headers[0].Get("Authorization") != "Bearer token" || c.headers[0].Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected headers: %+v", c.headers[0])
+		}
+		res := c.requests[0].ResourceLogs[0].Resource.Attributes
+		if len(res) != 1 || res[0].Key != "service.name" || *res[0].Value.StringValue != "arcadium" {
+			t.Errorf("Unexpected resource: %+v", res)
+		}
+	})
+
+	t.Run("span from context", func(t *testing.T) {
+		c := &collector{}
+		srv := httptest.NewServer(c)
+		defer srv.Close()
+
+		e := log.NewOTLPExporter(srv.URL)
+		defer e.Close()
+
+		sc := log.SpanContext{
+			TraceID: [16]byte{0xaa, 15: 0xbb},
+			SpanID:  [8]byte{0xcc, 7: 0xdd},
+			Flags:   1,
+		}
+		ctx := log.NewContextWithSpan(context.Background(), sc)
+		err := e.Fire(ctx, log.Entry{
+			Time:   time.Now(),
+			Level:  log.LevelError,
+			Fields: map[string]interface{}{"msg": "boom", "error": errors.New("failed")},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if err := e.Flush(); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		records := c.records()
+		if len(records) != 1 {
+			t.Fatalf("Unexpected records: %+v", records)
+		}
+		r := records[0]
+		if r.TraceID != "aa0000000000000000000000000000bb" || r.SpanID != "cc000000000000dd" || r.Flags != 1 ||
+			r.SeverityNumber != 17 || len(r.Attributes) != 1 || *r.Attributes[0].Value.StringValue != "failed" {
+			t.Errorf("Unexpected record: %+v", r)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		c := &collector{}
+		srv := httptest.NewServer(c)
+		defer srv.Close()
+
+		e := log.NewOTLPExporter(srv.URL, log.WithOTLPBatch(2, time.Hour))
+		defer e.Close()
+
+		l, err := log.New(log.WithOutput(log.NewStringBuffer()), log.WithOTLPExporter(e))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		for i := 0; i < 4; i++ {
+			l.Info("msg", "tick", "count", i)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(c.records()) < 4 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if len(c.records()) != 4 {
+			t.Fatalf("Unexpected records: %+v", c.records())
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, req := range c.requests {
+			if n := len(req.ResourceLogs[0].ScopeLogs[0].LogRecords); n != 2 {
+				t.Errorf("Unexpected batch size: %d", n)
+			}
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		c := &collector{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		srv := httptest.NewServer(c)
+		defer srv.Close()
+
+		e := log.NewOTLPExporter(srv.URL, log.WithOTLPRetry(2, time.Millisecond))
+		defer e.Close()
+
+		e.Fire(context.Background(), log.Entry{Time: time.Now(), Level: log.LevelInfo})
+		if err := e.Flush(); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(c.records()) != 1 {
+			t.Errorf("Unexpected records: %+v", c.records())
+		}
+	})
+
+	t.Run("permanent failure", func(t *testing.T) {
+		c := &collector{statuses: []int{http.StatusBadRequest}}
+		srv := httptest.NewServer(c)
+		defer srv.Close()
+
+		e := log.NewOTLPExporter(srv.URL, log.WithOTLPRetry(2, time.Millisecond))
+		defer e.Close()
+
+		e.Fire(context.Background(), log.Entry{Time: time.Now(), Level: log.LevelInfo})
+		err := e.Flush()
+		if err == nil || err.Error() != "failed to export log records: unexpected status 400" {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	})
+
+	t.Run("flush deadline", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		url := "http://" + l.Addr().String()
+		l.Close()
+
+		e := log.NewOTLPExporter(url, log.WithOTLPBatch(1, time.Hour), log.WithOTLPRetry(3, time.Second))
+		for i := 0; i < 3; i++ {
+			e.Fire(context.Background(), log.Entry{Time: time.Now(), Level: log.LevelInfo})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := e.FlushContext(ctx); err == nil {
+			t.Error("Expected an error")
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("Expected the flush to stop at the deadline, took %s", d)
+		}
+	})
+
+	t.Run("missing exporter", func(t *testing.T) {
+		_, err := log.New(log.WithFormat(log.FormatOTLP))
+		if !errors.Is(err, log.ErrMissingExporter) {
+			t.Errorf("\nExpected: %s\nActual:   %s", log.ErrMissingExporter, err)
+		}
+	})
+}
@@ -0,0 +1,478 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSyslogRetries     = 3
+	defaultSyslogBackoff     = 100 * time.Millisecond
+	defaultSyslogDialTimeout = 5 * time.Second
+	defaultSyslogQueueSize   = 1024
+
+	// RFC 5424 limits the timestamp to microsecond precision.
+	rfc5424Timestamp = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+type (
+	// SyslogWriter ships log entries to a syslog collector over udp, tcp or
+	// tls. It implements LevelWriter, mapping the level of each entry to a
+	// syslog severity. Entries are queued, and sent by a background
+	// goroutine, so an unavailable collector never blocks the logger. If a
+	// send fails, the goroutine reconnects and retries the send. Entries are
+	// dropped when the queue is full, or when the retries are exhausted.
+	SyslogWriter struct {
+		// pending counts the entries queued, or being sent, and dropped counts
+		// the entries dropped. They are first to guarantee 64-bit alignment
+		// for atomic access.
+		pending int64
+		dropped uint64
+
+		network   string
+		addr      string
+		format    SyslogFormat
+		facility  SyslogFacility
+		tag       string
+		hostname  string
+		tlsConfig *tls.Config
+		retries   int
+		backoff   time.Duration
+		timeout   time.Duration
+		queueSize int
+
+		dropMu sync.Mutex
+		onDrop func(reason string, n int)
+
+		mu     sync.Mutex
+		closed bool
+		queue  chan []byte
+		done   chan struct{}
+
+		// conn is only used by the goroutine sending the entries, once the
+		// writer is created.
+		conn net.Conn
+	}
+
+	// SyslogFormat defines the syslog message formats. Supported formats are
+	// SyslogRFC5424 (the default) and SyslogRFC3164.
+	SyslogFormat uint
+
+	// SyslogFacility is the syslog facility code attached to each message.
+	SyslogFacility uint
+
+	// SyslogOption provides for SyslogWriter configuration.
+	SyslogOption interface {
+		apply(*SyslogWriter)
+	}
+)
+
+const (
+	// SyslogRFC5424 formats messages according to RFC 5424.
+	SyslogRFC5424 SyslogFormat = iota
+
+	// SyslogRFC3164 formats messages according to the BSD syslog protocol,
+	// RFC 3164.
+	SyslogRFC3164
+
+	// SyslogFormatInvalid indicates an invalid syslog format.
+	SyslogFormatInvalid
+)
+
+// Syslog facilities, see RFC 5424 section 6.2.1.
+const (
+	SyslogKern            SyslogFacility = 0
+	SyslogUser            SyslogFacility = 1
+	SyslogDaemon          SyslogFacility = 3
+	SyslogLocal0          SyslogFacility = 16
+	SyslogLocal1          SyslogFacility = 17
+	SyslogLocal2          SyslogFacility = 18
+	SyslogLocal3          SyslogFacility = 19
+	SyslogLocal4          SyslogFacility = 20
+	SyslogLocal5          SyslogFacility = 21
+	SyslogLocal6          SyslogFacility = 22
+	SyslogLocal7          SyslogFacility = 23
+	SyslogFacilityInvalid SyslogFacility = 24
+)
+
+// Syslog severities, see RFC 5424 section 6.2.1.
+const (
+	severityEmergency = iota
+	severityAlert
+	severityCritical
+	severityError
+	severityWarning
+	severityNotice
+	severityInformational
+	severityDebug
+)
+
+var _ LevelWriter = (*SyslogWriter)(nil)
+
+// NewSyslogWriter returns a SyslogWriter connected to the syslog collector at
+// addr. The network must be one of "udp", "tcp" or "tls".
+func NewSyslogWriter(network, addr string, opts ...SyslogOption) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		network:  strings.ToLower(network),
+		addr:     addr,
+		format:   SyslogRFC5424,
+		facility: SyslogUser,
+		tag:      filepath.Base(os.Args[0]),
+		retries:  defaultSyslogRetries,
+		backoff:  defaultSyslogBackoff,
+		timeout:  defaultSyslogDialTimeout,
+	}
+	w.hostname, _ = os.Hostname()
+
+	for _, opt := range opts {
+		opt.apply(w)
+	}
+
+	switch w.network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidNetwork, network)
+	}
+	if w.format >= SyslogFormatInvalid {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSyslogFormat, w.format)
+	}
+	if w.facility >= SyslogFacilityInvalid {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSyslogFacility, w.facility)
+	}
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+	if w.queueSize <= 0 {
+		w.queueSize = defaultSyslogQueueSize
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog at %s: %w", addr, err)
+	}
+
+	w.queue = make(chan []byte, w.queueSize)
+	w.done = make(chan struct{})
+	go w.run()
+	return w, nil
+}
+
+// Write writes a log entry with the informational severity.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel("", p)
+}
+
+// WriteLevel queues a log entry with the severity corresponding to the given
+// level. It returns an error, without blocking, when the queue is full.
+func (w *SyslogWriter) WriteLevel(level string, p []byte) (int, error) {
+	msg := w.message(syslogSeverity(level), bytes.TrimRight(p, "\n"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, fmt.Errorf("failed to write to syslog at %s: writer closed", w.addr)
+	}
+	atomic.AddInt64(&w.pending, 1)
+	select {
+	case w.queue <- msg:
+		return len(p), nil
+	default:
+		atomic.AddInt64(&w.pending, -1)
+		w.drop(DropSyslogQueueFull)
+		return 0, fmt.Errorf("failed to write to syslog at %s: queue full", w.addr)
+	}
+}
+
+// Dropped returns the number of entries dropped, because the queue was full
+// or because the collector remained unavailable.
+func (w *SyslogWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Flush waits, up to 5s, for the queued entries to be sent.
+func (w *SyslogWriter) Flush() error {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	return w.FlushContext(ctx)
+}
+
+// FlushContext waits, until the context is done, for the queued entries to be
+// sent.
+func (w *SyslogWriter) FlushContext(ctx context.Context) error {
+	for atomic.LoadInt64(&w.pending) > 0 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt64(&w.pending); n > 0 {
+		return fmt.Errorf("failed to flush syslog at %s: %d entries pending", w.addr, n)
+	}
+	return nil
+}
+
+// Close sends the queued entries, waiting up to 5s, and closes the connection
+// to the syslog collector. The writer cannot be used once closed.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-time.After(flushTimeout):
+		return fmt.Errorf("failed to close syslog at %s: %d entries pending", w.addr, atomic.LoadInt64(&w.pending))
+	}
+}
+
+// run sends the queued entries until the writer is closed.
+func (w *SyslogWriter) run() {
+	defer close(w.done)
+
+	for msg := range w.queue {
+		w.send(msg)
+		atomic.AddInt64(&w.pending, -1)
+	}
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// send sends an entry. If the send fails, the connection is reestablished and
+// the send is retried. The entry is dropped once the retries are exhausted.
+func (w *SyslogWriter) send(msg []byte) {
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(w.backoff)
+		}
+		if w.conn == nil {
+			if err := w.connect(); err != nil {
+				continue
+			}
+		}
+		w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+		if _, err := w.conn.Write(msg); err == nil {
+			return
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	w.drop(DropSyslogSendFailed)
+}
+
+// countDrops implements dropCounter.
+func (w *SyslogWriter) countDrops(drops func(reason string, n int)) {
+	w.dropMu.Lock()
+	defer w.dropMu.Unlock()
+
+	w.onDrop = drops
+}
+
+// drop counts an entry dropped for the given reason, and reports it to the
+// logger.
+func (w *SyslogWriter) drop(reason string) {
+	atomic.AddUint64(&w.dropped, 1)
+
+	w.dropMu.Lock()
+	drops := w.onDrop
+	w.dropMu.Unlock()
+
+	if drops != nil {
+		drops(reason, 1)
+	}
+}
+
+func (w *SyslogWriter) connect() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: w.timeout}
+	if w.network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.addr, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(w.network, w.addr)
+	}
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// message builds the framed syslog message for the given severity and entry.
+func (w *SyslogWriter) message(severity int, p []byte) []byte {
+	var (
+		b   bytes.Buffer
+		pri = int(w.facility)*8 + severity
+		now = time.Now()
+	)
+
+	switch w.format {
+	case SyslogRFC3164:
+		fmt.Fprintf(&b, "<%d>%s %s %s[%d]: %s", pri, now.Format(time.Stamp), w.hostname, w.tag, os.Getpid(), p)
+	default:
+		fmt.Fprintf(&b, "<%d>1 %s %s %s %d - - %s", pri, now.Format(rfc5424Timestamp), w.hostname, w.tag, os.Getpid(), p)
+	}
+
+	// Datagrams carry a single message, streams need framing, see RFC 6587.
+	if w.network == "udp" {
+		return b.Bytes()
+	}
+	if w.format == SyslogRFC3164 {
+		b.WriteByte('\n')
+		return b.Bytes()
+	}
+	return append([]byte(fmt.Sprintf("%d ", b.Len())), b.Bytes()...)
+}
+
+// syslogSeverity maps a log level to a syslog severity.
+func syslogSeverity(level string) int {
+	switch level {
+	case "trace", "debug":
+		return severityDebug
+	case "warn":
+		return severityWarning
+	case "error":
+		return severityError
+	case "fatal":
+		return severityCritical
+	case "panic":
+		return severityAlert
+	default:
+		return severityInformational
+	}
+}
+
+// ToSyslogFormat translates the given syslog format as a string to a
+// SyslogFormat.
+func ToSyslogFormat(f string) SyslogFormat {
+	switch strings.ToLower(f) {
+	case "rfc5424", "": // An unset format string defaults to SyslogRFC5424.
+		return SyslogRFC5424
+	case "rfc3164":
+		return SyslogRFC3164
+	default:
+		return SyslogFormatInvalid
+	}
+}
+
+// ToSyslogFacility translates the given facility name as a string to a
+// SyslogFacility.
+func ToSyslogFacility(f string) SyslogFacility {
+	switch strings.ToLower(f) {
+	case "user", "": // An unset facility string defaults to SyslogUser.
+		return SyslogUser
+	case "kern":
+		return SyslogKern
+	case "daemon":
+		return SyslogDaemon
+	case "local0":
+		return SyslogLocal0
+	case "local1":
+		return SyslogLocal1
+	case "local2":
+		return SyslogLocal2
+	case "local3":
+		return SyslogLocal3
+	case "local4":
+		return SyslogLocal4
+	case "local5":
+		return SyslogLocal5
+	case "local6":
+		return SyslogLocal6
+	case "local7":
+		return SyslogLocal7
+	default:
+		return SyslogFacilityInvalid
+	}
+}
+
+// WithSyslogFormat sets the message format, the default is SyslogRFC5424.
+func WithSyslogFormat(format SyslogFormat) SyslogOption {
+	return newSyslogOption(func(w *SyslogWriter) {
+		w.format = format
+	})
+}
+
+// WithSyslogFacility sets the facility, the default is SyslogUser.
+func WithSyslogFacility(facility SyslogFacility) SyslogOption {
+	return newSyslogOption(func(w *SyslogWriter) {
+		w.facility = facility
+	})
+}
+
+// WithSyslogTag sets the tag (the APP-NAME in RFC 5424), the default is the
+// name of the running program.
+func WithSyslogTag(tag string) SyslogOption {
+	return newSyslogOption(func(w *SyslogWriter) {
+		if tag != "" {
+			w.tag = tag
+		}
+	})
+}
+
+// WithSyslogTLS sets the tls configuration used by the "tls" network.
+func WithSyslogTLS(cfg *tls.Config) SyslogOption {
+	return newSyslogOption(func(w *SyslogWriter) {
+		w.tlsConfig = cfg
+	})
+}
+
+// WithSyslogRetry sets the number of times a failed send is retried, and the
+// time to wait between attempts.
+func WithSyslogRetry(retries int, backoff time.Duration) SyslogOption {
+	return newSyslogOption(func(w *SyslogWriter) {
+		w.retries = retries
+		w.backoff = backoff
+	})
+}
+
+// WithSyslogQueueSize sets the number of entries queued for the collector,
+// the default is 1024.
+func WithSyslogQueueSize(size int) SyslogOption {
+	return newSyslogOption(func(w *SyslogWriter) {
+		w.queueSize = size
+	})
+}
+
+type (
+	syslogOption struct {
+		f func(*SyslogWriter)
+	}
+)
+
+func newSyslogOption(f func(*SyslogWriter)) syslogOption {
+	return syslogOption{f: f}
+}
+
+func (o syslogOption) apply(w *SyslogWriter) {
+	o.f(w)
+}
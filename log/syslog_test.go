@@ -0,0 +1,402 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"arcadium.dev/core/log"
+)
+
+func TestNewSyslogWriter(t *testing.T) {
+	t.Run("invalid network", func(t *testing.T) {
+		_, err := log.NewSyslogWriter("unix", "/dev/log")
+		if !errors.Is(err, log.ErrInvalidNetwork) {
+			t.Errorf("\nExpected: %s\nActual:   %s", log.ErrInvalidNetwork, err)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := log.NewSyslogWriter("udp", "127.0.0.1:514", log.WithSyslogFormat(log.SyslogFormat(42)))
+		if !errors.Is(err, log.ErrInvalidSyslogFormat) {
+			t.Errorf("\nExpected: %s\nActual:   %s", log.ErrInvalidSyslogFormat, err)
+		}
+	})
+
+	t.Run("invalid facility", func(t *testing.T) {
+		_, err := log.NewSyslogWriter("udp", "127.0.0.1:514", log.WithSyslogFacility(log.SyslogFacility(42)))
+		if !errors.Is(err, log.ErrInvalidSyslogFacility) {
+			t.Errorf("\nExpected: %s\nActual:   %s", log.ErrInvalidSyslogFacility, err)
+		}
+	})
+
+	t.Run("connect failure", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %s", err)
+		}
+		addr := l.Addr().String()
+		l.Close()
+
+		_, err = log.NewSyslogWriter("tcp", addr)
+		if err == nil || !strings.Contains(err.Error(), "failed to connect to syslog at "+addr) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	})
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer conn.Close()
+
+	w, err := log.NewSyslogWriter("udp", conn.LocalAddr().String(), log.WithSyslogTag("test"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer w.Close()
+
+	l := setupSyslogLogger(t, w)
+	l.Warn("msg", "hello")
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read: %s", err)
+	}
+
+	// facility user (1) * 8 + severity warning (4)
+	expected := regexp.MustCompile(`^<12>1 \S+ \S+ test ` + strconv.Itoa(os.Getpid()) + ` - - level=warn msg=hello$`)
+	if !expected.Match(buf[:n]) {
+		t.Errorf("Unexpected message: %q", buf[:n])
+	}
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	msgs := acceptSyslog(t, l, readOctetCounted)
+
+	w, err := log.NewSyslogWriter("tcp", l.Addr().String(), log.WithSyslogTag("test"), log.WithSyslogFacility(log.SyslogLocal0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer w.Close()
+
+	logger := setupSyslogLogger(t, w)
+	logger.Error("msg", "boom")
+	logger.Debug("msg", "details")
+	if _, err := w.WriteLevel("fatal", []byte("exiting")); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// facility local0 (16) * 8 + severity error (3)
+	checkSyslogMessage(t, msgs, `^<131>1 \S+ \S+ test \d+ - - level=error msg=boom$`)
+	// facility local0 (16) * 8 + severity debug (7)
+	checkSyslogMessage(t, msgs, `^<135>1 \S+ \S+ test \d+ - - level=debug msg=details$`)
+	// facility local0 (16) * 8 + severity critical (2)
+	checkSyslogMessage(t, msgs, `^<130>1 \S+ \S+ test \d+ - - exiting$`)
+}
+
+func TestSyslogWriterRFC3164(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	msgs := acceptSyslog(t, l, readLine)
+
+	w, err := log.NewSyslogWriter("tcp", l.Addr().String(),
+		log.WithSyslogTag("test"), log.WithSyslogFormat(log.SyslogRFC3164), log.WithSyslogFacility(log.SyslogDaemon),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer w.Close()
+
+	logger := setupSyslogLogger(t, w)
+	logger.Info("msg", "hello")
+
+	// facility daemon (3) * 8 + severity informational (6)
+	checkSyslogMessage(t, msgs, `^<30>[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} \S+ test\[\d+\]: level=info msg=hello$`)
+}
+
+func TestSyslogWriterTLS(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair("../test/insecure/cert.pem", "../test/insecure/key.pem")
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %s", err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	msgs := acceptSyslog(t, l, readOctetCounted)
+
+	ca, err := os.ReadFile("../test/insecure/rootCA.pem")
+	if err != nil {
+		t.Fatalf("Failed to load the CA certificate: %s", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	w, err := log.NewSyslogWriter("tls", l.Addr().String(),
+		log.WithSyslogTag("test"), log.WithSyslogTLS(&tls.Config{RootCAs: pool, ServerName: "testing"}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("plain entry\n")); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// facility user (1) * 8 + severity informational (6)
+	checkSyslogMessage(t, msgs, `^<14>1 \S+ \S+ test \d+ - - plain entry$`)
+}
+
+func TestSyslogWriterReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	w, err := log.NewSyslogWriter("tcp", l.Addr().String(), log.WithSyslogTag("test"), log.WithSyslogRetry(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer w.Close()
+
+	// Drop the connection, the writer must reconnect. The entries sent before
+	// the writer notices the dropped connection are lost.
+	(<-conns).Close()
+
+	var (
+		conn    net.Conn
+		timeout = time.After(5 * time.Second)
+	)
+	for conn == nil {
+		if _, err := w.WriteLevel("error", []byte("after reconnect")); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		select {
+		case conn = <-conns:
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("Timed out waiting for the reconnection")
+		}
+	}
+	msg, err := readOctetCounted(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !regexp.MustCompile(`^<11>1 \S+ \S+ test \d+ - - after reconnect$`).MatchString(msg) {
+		t.Errorf("Unexpected message: %q", msg)
+	}
+
+	// With the collector gone, the entries are dropped without blocking the
+	// writes.
+	l.Close()
+	conn.Close()
+	dropped := w.Dropped()
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Dropped() == dropped && time.Now().Before(deadline) {
+		start := time.Now()
+		if _, err := w.WriteLevel("error", []byte("lost")); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if time.Since(start) > 100*time.Millisecond {
+			t.Fatal("Expected the write not to block")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if w.Dropped() == dropped {
+		t.Error("Expected dropped entries")
+	}
+}
+
+func TestSyslogWriterClose(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	msgs := acceptSyslog(t, l, readOctetCounted)
+
+	w, err := log.NewSyslogWriter("tcp", l.Addr().String(), log.WithSyslogTag("test"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := w.WriteLevel("info", []byte("queued")); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	checkSyslogMessage(t, msgs, `^<14>1 \S+ \S+ test \d+ - - queued$`)
+
+	if _, err := w.WriteLevel("info", []byte("closed")); err == nil || !strings.Contains(err.Error(), "writer closed") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestToSyslogFormat(t *testing.T) {
+	formats := []struct {
+		s string
+		f log.SyslogFormat
+	}{
+		{s: "", f: log.SyslogRFC5424},
+		{s: "rfc5424", f: log.SyslogRFC5424},
+		{s: "RFC5424", f: log.SyslogRFC5424},
+		{s: "rfc3164", f: log.SyslogRFC3164},
+		{s: "RFC3164", f: log.SyslogRFC3164},
+		{s: "invalid", f: log.SyslogFormatInvalid},
+	}
+	for _, f := range formats {
+		if log.ToSyslogFormat(f.s) != f.f {
+			t.Errorf("Unexpected syslog format: %s, for %d", f.s, f.f)
+		}
+	}
+}
+
+func TestToSyslogFacility(t *testing.T) {
+	facilities := []struct {
+		s string
+		f log.SyslogFacility
+	}{
+		{s: "", f: log.SyslogUser},
+		{s: "user", f: log.SyslogUser},
+		{s: "kern", f: log.SyslogKern},
+		{s: "DAEMON", f: log.SyslogDaemon},
+		{s: "local0", f: log.SyslogLocal0},
+		{s: "local7", f: log.SyslogLocal7},
+		{s: "invalid", f: log.SyslogFacilityInvalid},
+	}
+	for _, f := range facilities {
+		if log.ToSyslogFacility(f.s) != f.f {
+			t.Errorf("Unexpected syslog facility: %s, for %d", f.s, f.f)
+		}
+	}
+}
+
+func setupSyslogLogger(t *testing.T, w *log.SyslogWriter) log.Logger {
+	t.Helper()
+
+	l, err := log.New(
+		log.WithLevel(log.LevelDebug),
+		log.WithOutput(w),
+		log.WithoutTimestamp(),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return l
+}
+
+// acceptSyslog accepts connections on the listener and sends each message,
+// read with the given framing, to the returned channel.
+func acceptSyslog(t *testing.T, l net.Listener, read func(*bufio.Reader) (string, error)) <-chan string {
+	t.Helper()
+
+	msgs := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := read(r)
+					if err != nil {
+						return
+					}
+					msgs <- msg
+				}
+			}()
+		}
+	}()
+	return msgs
+}
+
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+func checkSyslogMessage(t *testing.T, msgs <-chan string, pattern string) {
+	t.Helper()
+
+	select {
+	case msg := <-msgs:
+		if !regexp.MustCompile(pattern).MatchString(msg) {
+			t.Errorf("\nExpected: %s\nActual:   %q", pattern, msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Timed out waiting for message matching %s", pattern)
+	}
+}
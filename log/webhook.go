@@ -0,0 +1,123 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log // import "arcadium.dev/core/log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+type (
+	// WebhookHook is a Hook which POSTs each entry, encoded as a JSON object,
+	// to a webhook endpoint.
+	WebhookHook struct {
+		url     string
+		client  *http.Client
+		headers http.Header
+	}
+
+	// WebhookOption provides for WebhookHook configuration.
+	WebhookOption interface {
+		apply(*WebhookHook)
+	}
+)
+
+var _ Hook = (*WebhookHook)(nil)
+
+// NewWebhookHook returns a hook which posts entries to the given url.
+func NewWebhookHook(url string, opts ...WebhookOption) *WebhookHook {
+	h := &WebhookHook{
+		url:     url,
+		client:  &http.Client{Timeout: defaultWebhookTimeout},
+		headers: make(http.Header),
+	}
+	for _, opt := range opts {
+		opt.apply(h)
+	}
+	return h
+}
+
+// Fire posts the entry to the webhook endpoint. A response status other than
+// 2xx is returned as an error. The post is bounded by the timeout of the http
+// client, 10s by default, as the context given to a hook is never cancelled.
+func (h *WebhookHook) Fire(ctx context.Context, entry Entry) error {
+	kv := make([]interface{}, 0, len(entry.Fields)*2)
+	for k, v := range entry.Fields {
+		kv = append(kv, k, v)
+	}
+	var body bytes.Buffer
+	if err := log.NewJSONLogger(&body).Log(kv...); err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	for k, v := range h.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to post to webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WithWebhookClient sets the http client used to post entries.
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return newWebhookOption(func(h *WebhookHook) {
+		h.client = client
+	})
+}
+
+// WithWebhookHeader adds a header, e.g. an authorization token, to each post.
+func WithWebhookHeader(key, value string) WebhookOption {
+	return newWebhookOption(func(h *WebhookHook) {
+		h.headers.Add(key, value)
+	})
+}
+
+type (
+	webhookOption struct {
+		f func(*WebhookHook)
+	}
+)
+
+func newWebhookOption(f func(*WebhookHook)) webhookOption {
+	return webhookOption{f: f}
+}
+
+func (o webhookOption) apply(h *WebhookHook) {
+	o.f(h)
+}
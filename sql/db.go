@@ -83,7 +83,7 @@ var (
 
 				if err := db.PingContext(retryCtx); err != nil {
 					count++
-					logger.Info("msg", "ping failed, retrying...", "count", count, "error", err.Error())
+					logger.Info("msg", "ping failed, retrying...", "count", count, "error", err)
 					continue
 				}
 				done = true
@@ -0,0 +1,48 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"arcadium.dev/core/build"
+)
+
+var (
+	// ErrIncompatibleSchema is returned by CheckSchemaVersion when the schema
+	// version does not satisfy the constraint.
+	ErrIncompatibleSchema = errors.New("incompatible schema version")
+)
+
+// CheckSchemaVersion queries the version of the database schema, and checks
+// that it satisfies the constraint. The query must return a single semantic
+// version, e.g. "SELECT version FROM schema_version". A service should refuse
+// to start when the check fails.
+func (db *DB) CheckSchemaVersion(ctx context.Context, query string, constraint build.Constraint) (build.Semver, error) {
+	var s string
+	if err := db.QueryRowContext(ctx, query).Scan(&s); err != nil {
+		return build.Semver{}, fmt.Errorf("failed to query the schema version: %w", err)
+	}
+	v, err := build.ParseSemver(s)
+	if err != nil {
+		return build.Semver{}, fmt.Errorf("failed to parse the schema version: %w", err)
+	}
+	if !constraint.Check(v) {
+		return v, fmt.Errorf("%w: %s, %s is required", ErrIncompatibleSchema, v, constraint)
+	}
+	return v, nil
+}
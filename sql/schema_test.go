@@ -0,0 +1,74 @@
+// Copyright 2021-2022 arcadium.dev <info@arcadium.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"arcadium.dev/core/build"
+)
+
+func TestCheckSchemaVersion(t *testing.T) {
+	const query = "SELECT version FROM schema_version"
+	constraint := build.MustParseConstraint(">=1.4, <2")
+
+	tests := []struct {
+		name    string
+		version string
+		err     error
+		result  string
+	}{
+		{name: "compatible", version: "v1.4.2"},
+		{name: "too old", version: "1.3.9", result: "incompatible schema version: v1.3.9, >=1.4, <2 is required"},
+		{name: "too new", version: "2.0.0", result: "incompatible schema version: v2.0.0, >=1.4, <2 is required"},
+		{name: "invalid", version: "latest", result: "failed to parse the schema version"},
+		{name: "query failure", err: errors.New("no such table"), result: "failed to query the schema version: no such table"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock")
+			}
+			defer sdb.Close()
+
+			e := mock.ExpectQuery(query)
+			if test.err != nil {
+				e.WillReturnError(test.err)
+			} else {
+				e.WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(test.version))
+			}
+
+			db := &DB{DB: sdb}
+			_, err = db.CheckSchemaVersion(context.Background(), query, constraint)
+
+			switch {
+			case test.result == "" && err != nil:
+				t.Errorf("Unexpected error: %s", err)
+			case test.result != "" && (err == nil || !strings.Contains(err.Error(), test.result)):
+				t.Errorf("\nExpected error: %s\nActual error:   %s", test.result, err)
+			}
+			if strings.HasPrefix(test.result, "incompatible") && !errors.Is(err, ErrIncompatibleSchema) {
+				t.Errorf("Expected ErrIncompatibleSchema: %s", err)
+			}
+		})
+	}
+}